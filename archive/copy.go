@@ -0,0 +1,218 @@
+package archive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Export formats supported by ConvertCopy.
+const (
+	FormatCSV    = "csv"
+	FormatSQL    = "sql"
+	FormatInsert = "insert"
+)
+
+var copyHeaderPattern = regexp.MustCompile(`^COPY (.+?) \((.*)\) FROM stdin;$`)
+
+// copyBlock is the table and column list of a COPY statement. Names are
+// kept as printed by pg_restore, i.e. quoted where needed.
+type copyBlock struct {
+	table   string
+	columns []string
+}
+
+// ConvertCopy reads a data-only SQL script produced by pg_restore and writes
+// the rows of its COPY blocks in format: CSV with a header line, COPY SQL
+// that psql can load, or one INSERT statement per row. Statements outside
+// COPY blocks are dropped. It returns the number of rows written.
+func ConvertCopy(r io.Reader, w io.Writer, format string) (int, error) {
+	if format != FormatCSV && format != FormatSQL && format != FormatInsert {
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	var (
+		block *copyBlock
+		rows  int
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return rows, err
+		}
+		if line == "" && errors.Is(err, io.EOF) {
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case block == nil:
+			if match := copyHeaderPattern.FindStringSubmatch(line); match != nil {
+				block = &copyBlock{table: match[1], columns: strings.Split(match[2], ", ")}
+				if err := writeBlockStart(writer, block, format); err != nil {
+					return rows, err
+				}
+			}
+		case line == `\.`:
+			if format == FormatSQL {
+				if _, err := writer.WriteString(`\.` + "\n\n"); err != nil {
+					return rows, err
+				}
+			}
+			block = nil
+		default:
+			if err := writeRow(writer, block, line, format); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if block != nil {
+		return rows, errors.New("unterminated COPY block")
+	}
+	return rows, writer.Flush()
+}
+
+func writeBlockStart(w *bufio.Writer, block *copyBlock, format string) error {
+	var err error
+	switch format {
+	case FormatCSV:
+		names := make([]string, 0, len(block.columns))
+		for _, column := range block.columns {
+			names = append(names, csvField(unquoteIdentifier(column), false))
+		}
+		_, err = w.WriteString(strings.Join(names, ",") + "\n")
+	case FormatSQL:
+		_, err = fmt.Fprintf(w, "COPY %s (%s) FROM stdin;\n", block.table, strings.Join(block.columns, ", "))
+	}
+	return err
+}
+
+func writeRow(w *bufio.Writer, block *copyBlock, line, format string) error {
+	if format == FormatSQL {
+		_, err := w.WriteString(line + "\n")
+		return err
+	}
+
+	fields := strings.Split(line, "\t")
+	if len(fields) != len(block.columns) {
+		return fmt.Errorf("row of %s has %d fields, expected %d", block.table, len(fields), len(block.columns))
+	}
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, null := unescapeCopy(field)
+		if format == FormatCSV {
+			values = append(values, csvField(value, null))
+		} else {
+			values = append(values, sqlLiteral(value, null))
+		}
+	}
+
+	var err error
+	if format == FormatCSV {
+		_, err = w.WriteString(strings.Join(values, ",") + "\n")
+	} else {
+		_, err = fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES (%s);\n", block.table, strings.Join(block.columns, ", "), strings.Join(values, ", "))
+	}
+	return err
+}
+
+// unescapeCopy decodes a field of the COPY text format and reports whether
+// it is NULL.
+func unescapeCopy(field string) (string, bool) {
+	if field == `\N` {
+		return "", true
+	}
+	if !strings.Contains(field, `\`) {
+		return field, false
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 == len(field) {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		switch next := field[i]; next {
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'v':
+			out.WriteByte('\v')
+		case 'x':
+			end := i + 1
+			for end < len(field) && end < i+3 && isHexDigit(field[end]) {
+				end++
+			}
+			if end == i+1 {
+				out.WriteByte('x')
+				continue
+			}
+			value, _ := strconv.ParseUint(field[i+1:end], 16, 8)
+			out.WriteByte(byte(value))
+			i = end - 1
+		default:
+			if next >= '0' && next <= '7' {
+				end := i
+				for end < len(field) && end < i+3 && field[end] >= '0' && field[end] <= '7' {
+					end++
+				}
+				value, _ := strconv.ParseUint(field[i:end], 8, 8)
+				out.WriteByte(byte(value))
+				i = end - 1
+				continue
+			}
+			out.WriteByte(next)
+		}
+	}
+	return out.String(), false
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// csvField quotes value like PostgreSQL's CSV output: NULL is an empty
+// unquoted field and empty strings are quoted.
+func csvField(value string, null bool) string {
+	if null {
+		return ""
+	}
+	if value == "" || strings.ContainsAny(value, ",\"\r\n") {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return value
+}
+
+func sqlLiteral(value string, null bool) string {
+	if null {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func unquoteIdentifier(name string) string {
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return name
+}
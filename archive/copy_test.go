@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const dataScript = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+--
+-- Data for Name: items; Type: TABLE DATA; Schema: public; Owner: postgres
+--
+
+COPY public.items (id, name, "Note") FROM stdin;
+1	alpha	\N
+2	it's, "quoted"	line\nbreak
+3		tab\there
+\.
+
+
+--
+-- PostgreSQL database dump complete
+--
+`
+
+func TestConvertCopy(t *testing.T) {
+	tests := map[string]string{
+		FormatCSV: "id,name,Note\n" +
+			"1,alpha,\n" +
+			"2,\"it's, \"\"quoted\"\"\",\"line\nbreak\"\n" +
+			"3,\"\",tab\there\n",
+		FormatInsert: "INSERT INTO public.items (id, name, \"Note\") VALUES ('1', 'alpha', NULL);\n" +
+			"INSERT INTO public.items (id, name, \"Note\") VALUES ('2', 'it''s, \"quoted\"', 'line\nbreak');\n" +
+			"INSERT INTO public.items (id, name, \"Note\") VALUES ('3', '', 'tab\there');\n",
+		FormatSQL: "COPY public.items (id, name, \"Note\") FROM stdin;\n" +
+			"1\talpha\t\\N\n" +
+			"2\tit's, \"quoted\"\tline\\nbreak\n" +
+			"3\t\ttab\\there\n" +
+			"\\.\n\n",
+	}
+	for format, expected := range tests {
+		var out bytes.Buffer
+		rows, err := ConvertCopy(strings.NewReader(dataScript), &out, format)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		if rows != 3 {
+			t.Fatalf("%s: expected 3 rows, got %d", format, rows)
+		}
+		if out.String() != expected {
+			t.Fatalf("%s: unexpected output:\n%s", format, out.String())
+		}
+	}
+
+	if _, err := ConvertCopy(strings.NewReader(dataScript), &bytes.Buffer{}, "xml"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+	if _, err := ConvertCopy(strings.NewReader("COPY public.items (id) FROM stdin;\n1\n"), &bytes.Buffer{}, FormatCSV); err == nil {
+		t.Fatal("expected error for unterminated block")
+	}
+}
+
+func TestUnescapeCopy(t *testing.T) {
+	tests := map[string]string{
+		`plain`:     "plain",
+		`a\\b`:      `a\b`,
+		`\x41\101`:  "AA",
+		`\r\n\t\v`:  "\r\n\t\v",
+		`trailing\`: `trailing\`,
+	}
+	for input, expected := range tests {
+		if value, null := unescapeCopy(input); null || value != expected {
+			t.Fatalf("%q: expected %q, got %q (null=%v)", input, expected, value, null)
+		}
+	}
+	if _, null := unescapeCopy(`\N`); !null {
+		t.Fatal(`expected \N to be NULL`)
+	}
+}
@@ -0,0 +1,192 @@
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SchemaObject is one object of a schema-only SQL script, identified by the
+// "-- Name: ...; Type: ...; Schema: ..." comment pg_restore prints above it.
+type SchemaObject struct {
+	Type   string
+	Schema string
+	Name   string
+	Lines  []string
+}
+
+// Key identifies the object across scripts.
+func (o SchemaObject) Key() string {
+	return o.Type + " " + o.Qualified()
+}
+
+// Qualified returns the schema qualified name.
+func (o SchemaObject) Qualified() string {
+	if o.Schema == "" || o.Schema == "-" {
+		return o.Name
+	}
+	return o.Schema + "." + o.Name
+}
+
+var objectHeaderPattern = regexp.MustCompile(`^-- (?:Data for )?Name: (.+?); Type: (.+?); Schema: (.+?)(?:; Owner: .*)?$`)
+
+// ParseSchema splits the output of `pg_restore -s -f -` into objects.
+// Comments, blank lines and the session settings preceding the first object
+// are dropped so that scripts of different dumps compare equal.
+func ParseSchema(r io.Reader) ([]SchemaObject, error) {
+	var (
+		objects []SchemaObject
+		current *SchemaObject
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if match := objectHeaderPattern.FindStringSubmatch(line); match != nil {
+			objects = append(objects, SchemaObject{Name: match[1], Type: match[2], Schema: match[3]})
+			current = &objects[len(objects)-1]
+			continue
+		}
+		if current == nil || line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		current.Lines = append(current.Lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read schema script: %w", err)
+	}
+	return objects, nil
+}
+
+// Change kinds reported by DiffSchemas.
+const (
+	Added   = "+"
+	Removed = "-"
+	Changed = "~"
+)
+
+// SchemaChange describes an object that differs between two scripts. Lines
+// holds the line diff of changed objects, prefixed with "-" or "+".
+type SchemaChange struct {
+	Kind   string
+	Object SchemaObject
+	Lines  []string
+}
+
+// DiffSchemas compares the objects of two scripts. Changes are ordered by
+// object type and name.
+func DiffSchemas(before, after []SchemaObject) []SchemaChange {
+	beforeByKey := groupObjects(before)
+	afterByKey := groupObjects(after)
+
+	var changes []SchemaChange
+	for key, old := range beforeByKey {
+		current, ok := afterByKey[key]
+		if !ok {
+			changes = append(changes, SchemaChange{Kind: Removed, Object: old})
+			continue
+		}
+		if lines := diffLines(old.Lines, current.Lines); len(lines) > 0 {
+			changes = append(changes, SchemaChange{Kind: Changed, Object: current, Lines: lines})
+		}
+	}
+	for key, current := range afterByKey {
+		if _, ok := beforeByKey[key]; !ok {
+			changes = append(changes, SchemaChange{Kind: Added, Object: current})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i].Object, changes[j].Object
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Qualified() < b.Qualified()
+	})
+	return changes
+}
+
+// groupObjects indexes objects by key. Objects sharing a key, such as
+// several comments on one table, are merged.
+func groupObjects(objects []SchemaObject) map[string]SchemaObject {
+	grouped := map[string]SchemaObject{}
+	for _, object := range objects {
+		if existing, ok := grouped[object.Key()]; ok {
+			existing.Lines = append(existing.Lines, object.Lines...)
+			grouped[object.Key()] = existing
+			continue
+		}
+		object.Lines = append([]string(nil), object.Lines...)
+		grouped[object.Key()] = object
+	}
+	return grouped
+}
+
+// diffLines returns a minimal line diff based on the longest common
+// subsequence. Unchanged lines are omitted.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+"+b[j])
+	}
+	return lines
+}
+
+// PrintSchemaChanges writes changes grouped by object type followed by a
+// summary line.
+func PrintSchemaChanges(w io.Writer, changes []SchemaChange) {
+	counts := map[string]int{}
+	currentType := ""
+	for _, change := range changes {
+		if change.Object.Type != currentType {
+			if currentType != "" {
+				fmt.Fprintln(w)
+			}
+			currentType = change.Object.Type
+			fmt.Fprintf(w, "%s\n", currentType)
+		}
+		counts[change.Kind]++
+		fmt.Fprintf(w, "  %s %s\n", change.Kind, change.Object.Qualified())
+		for _, line := range change.Lines {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+	if len(changes) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d added, %d removed, %d changed\n", counts[Added], counts[Removed], counts[Changed])
+}
@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const schemaBefore = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+
+--
+-- Name: items; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.items (
+    id integer NOT NULL,
+    name text
+);
+
+
+ALTER TABLE public.items OWNER TO postgres;
+
+--
+-- Name: add(integer, integer); Type: FUNCTION; Schema: public; Owner: postgres
+--
+
+CREATE FUNCTION public.add(a integer, b integer) RETURNS integer
+    LANGUAGE sql
+    AS $$ SELECT a + b $$;
+
+--
+-- Name: items items_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.items
+    ADD CONSTRAINT items_pkey PRIMARY KEY (id);
+`
+
+const schemaAfter = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 30;
+
+--
+-- Name: items; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.items (
+    id integer NOT NULL,
+    name text NOT NULL,
+    created_at timestamp with time zone
+);
+
+
+ALTER TABLE public.items OWNER TO postgres;
+
+--
+-- Name: items items_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.items
+    ADD CONSTRAINT items_pkey PRIMARY KEY (id);
+
+--
+-- Name: items_name_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX items_name_idx ON public.items USING btree (name);
+`
+
+func TestDiffSchemas(t *testing.T) {
+	before, err := ParseSchema(strings.NewReader(schemaBefore))
+	if err != nil {
+		t.Fatalf("ParseSchema returned error: %v", err)
+	}
+	after, err := ParseSchema(strings.NewReader(schemaAfter))
+	if err != nil {
+		t.Fatalf("ParseSchema returned error: %v", err)
+	}
+	if len(before) != 3 || before[1].Key() != "FUNCTION public.add(integer, integer)" {
+		t.Fatalf("unexpected objects: %+v", before)
+	}
+
+	changes := DiffSchemas(before, after)
+	var out bytes.Buffer
+	PrintSchemaChanges(&out, changes)
+
+	expected := `FUNCTION
+  - public.add(integer, integer)
+
+INDEX
+  + public.items_name_idx
+
+TABLE
+  ~ public.items
+      -    name text
+      +    name text NOT NULL,
+      +    created_at timestamp with time zone
+
+1 added, 1 removed, 1 changed
+`
+	if out.String() != expected {
+		t.Fatalf("unexpected diff:\n%s", out.String())
+	}
+}
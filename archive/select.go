@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Table is a schema qualified table name.
+type Table struct {
+	Schema string
+	Name   string
+}
+
+// ParseTable parses "schema.table", defaulting to the public schema.
+func ParseTable(value string) Table {
+	if schema, name, ok := strings.Cut(value, "."); ok {
+		return Table{Schema: schema, Name: name}
+	}
+	return Table{Schema: "public", Name: value}
+}
+
+func (t Table) String() string {
+	return QuoteIdentifier(t.Schema) + "." + QuoteIdentifier(t.Name)
+}
+
+// tableObjectTypes are entries attached to a table whose name starts with
+// the table name, e.g. "CONSTRAINT public items items_pkey".
+var tableObjectTypes = map[string]bool{
+	"CONSTRAINT": true, "FK CONSTRAINT": true, "CHECK CONSTRAINT": true, "DEFAULT": true,
+	"TRIGGER": true, "POLICY": true, "ROW SECURITY": true, "RULE": true,
+}
+
+// Select returns the entries that belong to the given tables or schemas.
+// Entries of a table are its definition, data, constraints, defaults,
+// triggers, policies and rules; indexes cannot be attributed to a table
+// from the listing and are only selected through their schema. SCHEMA
+// entries themselves are never selected.
+func Select(entries []Entry, tables []Table, schemas []string) []Entry {
+	var selected []Entry
+	for _, entry := range entries {
+		if entry.Type != "SCHEMA" && matches(entry, tables, schemas) {
+			selected = append(selected, entry)
+		}
+	}
+	return selected
+}
+
+func matches(entry Entry, tables []Table, schemas []string) bool {
+	for _, schema := range schemas {
+		if entry.Schema == schema {
+			return true
+		}
+	}
+	for _, table := range tables {
+		if entry.Schema != table.Schema {
+			continue
+		}
+		switch {
+		case entry.Type == "TABLE" || entry.Type == "TABLE DATA":
+			if entry.Name == table.Name {
+				return true
+			}
+		case tableObjectTypes[entry.Type]:
+			if strings.HasPrefix(entry.Name, table.Name+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OfType keeps the entries whose type is one of types.
+func OfType(entries []Entry, types ...string) []Entry {
+	var kept []Entry
+	for _, entry := range entries {
+		for _, entryType := range types {
+			if entry.Type == entryType {
+				kept = append(kept, entry)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// QuoteIdentifier quotes name the way pg_dump does for identifiers that are
+// not plain lower case names.
+func QuoteIdentifier(name string) string {
+	if plainIdentifier.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// RenameSchema rewrites qualified references to tables in a SQL script
+// generated by pg_restore so that they point to schema instead. The data rows
+// of COPY statements are left as they are.
+func RenameSchema(script string, tables []Table, schema string) string {
+	type rename struct {
+		pattern *regexp.Regexp
+		value   string
+	}
+	var renames []rename
+	for _, table := range tables {
+		renames = append(renames, rename{
+			pattern: regexp.MustCompile(`(^|[^\w".$])` + regexp.QuoteMeta(table.String()) + `($|[^\w"$])`),
+			value:   "${1}" + strings.ReplaceAll(QuoteIdentifier(schema)+"."+QuoteIdentifier(table.Name), "$", "$$") + "${2}",
+		})
+	}
+
+	lines := strings.Split(script, "\n")
+	copying := false
+	for i, line := range lines {
+		if copying {
+			copying = line != `\.`
+			continue
+		}
+		for _, rename := range renames {
+			line = rename.pattern.ReplaceAllString(line, rename.value)
+		}
+		lines[i] = line
+		copying = strings.HasPrefix(line, "COPY ") && strings.HasSuffix(line, " FROM stdin;")
+	}
+	return strings.Join(lines, "\n")
+}
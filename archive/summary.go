@@ -0,0 +1,88 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Summary groups the objects of an archive by kind.
+type Summary struct {
+	Header       Header   `json:"header"`
+	Entries      int      `json:"entries"`
+	Schemas      []string `json:"schemas"`
+	Extensions   []string `json:"extensions"`
+	Tables       []string `json:"tables"`
+	Views        []string `json:"views"`
+	Indexes      []string `json:"indexes"`
+	Functions    []string `json:"functions"`
+	LargeObjects int      `json:"large_objects"`
+}
+
+// Summarize builds the summary of toc. Table names are schema qualified.
+func Summarize(toc TOC) Summary {
+	summary := Summary{Header: toc.Header, Entries: len(toc.Entries)}
+	schemas := map[string]bool{}
+	for _, entry := range toc.Entries {
+		qualified := entry.Name
+		if entry.Schema != "" {
+			qualified = entry.Schema + "." + entry.Name
+			schemas[entry.Schema] = true
+		}
+		switch entry.Type {
+		case "SCHEMA":
+			schemas[entry.Name] = true
+		case "EXTENSION":
+			summary.Extensions = append(summary.Extensions, entry.Name)
+		case "TABLE", "FOREIGN TABLE":
+			summary.Tables = append(summary.Tables, qualified)
+		case "VIEW", "MATERIALIZED VIEW":
+			summary.Views = append(summary.Views, qualified)
+		case "INDEX":
+			summary.Indexes = append(summary.Indexes, qualified)
+		case "FUNCTION", "PROCEDURE", "AGGREGATE":
+			summary.Functions = append(summary.Functions, qualified)
+		case "BLOB", "BLOB METADATA":
+			summary.LargeObjects++
+		}
+	}
+	for schema := range schemas {
+		summary.Schemas = append(summary.Schemas, schema)
+	}
+	for _, names := range [][]string{summary.Schemas, summary.Extensions, summary.Tables, summary.Views, summary.Indexes, summary.Functions} {
+		sort.Strings(names)
+	}
+	return summary
+}
+
+// Print writes the human readable summary.
+func (s Summary) Print(w io.Writer) {
+	header := s.Header
+	fmt.Fprintf(w, "database:        %s\n", header.DBName)
+	fmt.Fprintf(w, "created:         %s\n", header.Created)
+	fmt.Fprintf(w, "server version:  %s\n", header.ServerVersion)
+	fmt.Fprintf(w, "pg_dump version: %s\n", header.PgDumpVersion)
+	fmt.Fprintf(w, "format:          %s (dump version %s)\n", header.Format, header.DumpVersion)
+	fmt.Fprintf(w, "compression:     %s\n", header.Compression)
+	fmt.Fprintf(w, "entries:         %d\n", s.Entries)
+	fmt.Fprintf(w, "large objects:   %d\n", s.LargeObjects)
+
+	sections := []struct {
+		title string
+		names []string
+	}{
+		{"schemas", s.Schemas},
+		{"extensions", s.Extensions},
+		{"tables", s.Tables},
+		{"views", s.Views},
+		{"indexes", s.Indexes},
+		{"functions", s.Functions},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n%s (%d)\n", section.title, len(section.names))
+		if len(section.names) > 0 {
+			fmt.Fprintf(w, "  %s\n", strings.Join(section.names, "\n  "))
+		}
+	}
+}
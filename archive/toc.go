@@ -0,0 +1,166 @@
+// Package archive reads the table of contents of pg_dump custom format
+// archives as printed by `pg_restore -l`.
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Header holds the archive properties printed above the entries.
+type Header struct {
+	Created       string `json:"created"`
+	DBName        string `json:"dbname"`
+	Entries       int    `json:"toc_entries"`
+	Compression   string `json:"compression"`
+	DumpVersion   string `json:"dump_version"`
+	Format        string `json:"format"`
+	ServerVersion string `json:"server_version"`
+	PgDumpVersion string `json:"pg_dump_version"`
+}
+
+// Entry is a single TOC line such as
+// "215; 1259 16386 TABLE public items postgres". Schema is empty for
+// objects that do not belong to a schema.
+type Entry struct {
+	ID     int    `json:"id"`
+	Type   string `json:"type"`
+	Schema string `json:"schema,omitempty"`
+	Name   string `json:"name"`
+	Owner  string `json:"owner,omitempty"`
+	Line   string `json:"-"`
+}
+
+// TOC is a parsed archive listing.
+type TOC struct {
+	Header  Header  `json:"header"`
+	Entries []Entry `json:"entries"`
+}
+
+// entryTypes lists the object descriptions pg_dump uses, longest first so
+// that "TABLE DATA" wins over "TABLE".
+var entryTypes = func() []string {
+	types := []string{
+		"ACCESS METHOD", "ACL", "AGGREGATE", "BLOB", "BLOB METADATA", "BLOBS", "CAST",
+		"CHECK CONSTRAINT", "COLLATION", "COMMENT", "CONSTRAINT", "CONVERSION",
+		"DATABASE", "DATABASE PROPERTIES", "DEFAULT", "DEFAULT ACL", "DOMAIN",
+		"ENCODING", "EVENT TRIGGER", "EXTENSION", "FK CONSTRAINT", "FOREIGN DATA WRAPPER",
+		"FOREIGN SERVER", "FOREIGN TABLE", "FUNCTION", "INDEX", "INDEX ATTACH",
+		"LARGE OBJECTS", "MATERIALIZED VIEW", "MATERIALIZED VIEW DATA", "OPERATOR",
+		"OPERATOR CLASS", "OPERATOR FAMILY", "POLICY", "PROCEDURE", "PROCEDURAL LANGUAGE",
+		"PUBLICATION", "PUBLICATION TABLE", "PUBLICATION TABLES IN SCHEMA", "ROW SECURITY",
+		"RULE", "SCHEMA", "SECURITY LABEL", "SEQUENCE", "SEQUENCE OWNED BY", "SEQUENCE SET",
+		"SERVER", "SHELL TYPE", "STATISTICS", "STDSTRINGS", "SEARCHPATH", "SUBSCRIPTION",
+		"TABLE", "TABLE ATTACH", "TABLE DATA", "TEXT SEARCH CONFIGURATION",
+		"TEXT SEARCH DICTIONARY", "TEXT SEARCH PARSER", "TEXT SEARCH TEMPLATE",
+		"TRANSFORM", "TRIGGER", "TYPE", "USER MAPPING", "VIEW",
+	}
+	sort.Slice(types, func(i, j int) bool { return len(types[i]) > len(types[j]) })
+	return types
+}()
+
+// ownerlessTypes are printed without a trailing owner.
+var ownerlessTypes = map[string]bool{"EXTENSION": true, "ENCODING": true, "STDSTRINGS": true, "SEARCHPATH": true}
+
+var entryPattern = regexp.MustCompile(`^(\d+); (\d+) (\d+) (.*)$`)
+
+// Parse reads the output of `pg_restore -l`.
+func Parse(r io.Reader) (TOC, error) {
+	var toc TOC
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment, ok := strings.CutPrefix(line, ";"); ok {
+			parseHeaderLine(&toc.Header, strings.TrimSpace(comment))
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry, err := parseEntry(line)
+		if err != nil {
+			return TOC{}, err
+		}
+		toc.Entries = append(toc.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return TOC{}, fmt.Errorf("read archive listing: %w", err)
+	}
+	return toc, nil
+}
+
+func parseHeaderLine(header *Header, line string) {
+	if created, ok := strings.CutPrefix(line, "Archive created at "); ok {
+		header.Created = created
+		return
+	}
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+	switch strings.TrimSpace(key) {
+	case "dbname":
+		header.DBName = value
+	case "TOC Entries":
+		header.Entries, _ = strconv.Atoi(value)
+	case "Compression":
+		header.Compression = value
+	case "Dump Version":
+		header.DumpVersion = value
+	case "Format":
+		header.Format = value
+	case "Dumped from database version":
+		header.ServerVersion = value
+	case "Dumped by pg_dump version":
+		header.PgDumpVersion = value
+	}
+}
+
+func parseEntry(line string) (Entry, error) {
+	match := entryPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Entry{}, fmt.Errorf("unrecognized archive entry %q", line)
+	}
+	id, _ := strconv.Atoi(match[1])
+	entry := Entry{ID: id, Line: line}
+
+	rest := match[4]
+	for _, entryType := range entryTypes {
+		if after, ok := strings.CutPrefix(rest, entryType+" "); ok {
+			entry.Type, rest = entryType, after
+			break
+		}
+	}
+	if entry.Type == "" {
+		return Entry{}, fmt.Errorf("unknown object type in archive entry %q", line)
+	}
+
+	schema, rest, _ := strings.Cut(rest, " ")
+	if schema != "-" {
+		entry.Schema = schema
+	}
+	if !ownerlessTypes[entry.Type] {
+		if i := strings.LastIndex(rest, " "); i >= 0 {
+			rest, entry.Owner = rest[:i], rest[i+1:]
+		}
+	}
+	entry.Name = rest
+	return entry, nil
+}
+
+// WriteList writes entries in the format accepted by `pg_restore -L`.
+func WriteList(w io.Writer, entries []Entry) error {
+	for _, entry := range entries {
+		if _, err := fmt.Fprintln(w, entry.Line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
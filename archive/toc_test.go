@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+)
+
+const listing = `;
+; Archive created at 2025-07-04 09:00:01 UTC
+;     dbname: app
+;     TOC Entries: 12
+;     Compression: gzip
+;     Dump Version: 1.15-0
+;     Format: CUSTOM
+;     Integer: 4 bytes
+;     Offset: 8 bytes
+;     Dumped from database version: 16.3
+;     Dumped by pg_dump version: 16.3
+;
+;
+; Selected TOC Entries:
+;
+6; 2615 16390 SCHEMA - audit postgres
+2; 3079 16400 EXTENSION - pgcrypto
+3352; 0 0 COMMENT - EXTENSION pgcrypto
+216; 1255 16401 FUNCTION public add(integer, integer) postgres
+215; 1259 16386 TABLE public items postgres
+214; 1259 16385 SEQUENCE public items_id_seq postgres
+3194; 2604 16389 DEFAULT public items id postgres
+217; 1259 16410 TABLE audit events postgres
+3343; 0 16386 TABLE DATA public items postgres
+3344; 0 16410 TABLE DATA audit events postgres
+3351; 0 0 SEQUENCE SET public items_id_seq postgres
+3196; 2606 16391 CONSTRAINT public items items_pkey postgres
+3200; 1259 16392 INDEX public items_name_idx postgres
+`
+
+func TestParse(t *testing.T) {
+	toc, err := Parse(strings.NewReader(listing))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	header := toc.Header
+	if header.DBName != "app" || header.Entries != 12 || header.Compression != "gzip" || header.ServerVersion != "16.3" || header.Created != "2025-07-04 09:00:01 UTC" {
+		t.Fatalf("unexpected header: %+v", header)
+	}
+	if len(toc.Entries) != 13 {
+		t.Fatalf("expected 13 entries, got %d", len(toc.Entries))
+	}
+
+	expected := map[int]Entry{
+		2:    {Type: "EXTENSION", Name: "pgcrypto"},
+		216:  {Type: "FUNCTION", Schema: "public", Name: "add(integer, integer)", Owner: "postgres"},
+		3343: {Type: "TABLE DATA", Schema: "public", Name: "items", Owner: "postgres"},
+		3351: {Type: "SEQUENCE SET", Schema: "public", Name: "items_id_seq", Owner: "postgres"},
+		3196: {Type: "CONSTRAINT", Schema: "public", Name: "items items_pkey", Owner: "postgres"},
+	}
+	for _, entry := range toc.Entries {
+		want, ok := expected[entry.ID]
+		if !ok {
+			continue
+		}
+		if entry.Type != want.Type || entry.Schema != want.Schema || entry.Name != want.Name || entry.Owner != want.Owner {
+			t.Fatalf("entry %d: expected %+v, got %+v", entry.ID, want, entry)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("garbage line\n")); err == nil {
+		t.Fatal("expected error for unrecognized line")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	toc, err := Parse(strings.NewReader(listing))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	ids := func(entries []Entry) []int {
+		var result []int
+		for _, entry := range entries {
+			result = append(result, entry.ID)
+		}
+		return result
+	}
+
+	tables := ids(Select(toc.Entries, []Table{ParseTable("items")}, nil))
+	if len(tables) != 4 || tables[0] != 215 || tables[1] != 3194 || tables[2] != 3343 || tables[3] != 3196 {
+		t.Fatalf("unexpected table selection: %v", tables)
+	}
+
+	schemas := ids(Select(toc.Entries, nil, []string{"audit"}))
+	if len(schemas) != 2 || schemas[0] != 217 || schemas[1] != 3344 {
+		t.Fatalf("unexpected schema selection: %v", schemas)
+	}
+
+	data := ids(OfType(Select(toc.Entries, []Table{ParseTable("public.items")}, nil), "TABLE DATA"))
+	if len(data) != 1 || data[0] != 3343 {
+		t.Fatalf("unexpected data selection: %v", data)
+	}
+}
+
+func TestRenameSchema(t *testing.T) {
+	script := "CREATE TABLE public.items (\n    id integer,\n    kind public.item_kind\n);\n" +
+		"COPY public.items (id, kind) FROM stdin;\n" +
+		"1\tsee public.items\n" +
+		"\\.\n" +
+		"ALTER TABLE ONLY public.items ADD CONSTRAINT items_pkey PRIMARY KEY (id);\n" +
+		"CREATE TABLE public.items_archive (id integer);\n"
+
+	renamed := RenameSchema(script, []Table{ParseTable("items")}, "Restored")
+	expected := "CREATE TABLE \"Restored\".items (\n    id integer,\n    kind public.item_kind\n);\n" +
+		"COPY \"Restored\".items (id, kind) FROM stdin;\n" +
+		"1\tsee public.items\n" +
+		"\\.\n" +
+		"ALTER TABLE ONLY \"Restored\".items ADD CONSTRAINT items_pkey PRIMARY KEY (id);\n" +
+		"CREATE TABLE public.items_archive (id integer);\n"
+	if renamed != expected {
+		t.Fatalf("unexpected script:\n%s", renamed)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	toc, err := Parse(strings.NewReader(listing))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	summary := Summarize(toc)
+	if strings.Join(summary.Schemas, ",") != "audit,public" {
+		t.Fatalf("unexpected schemas: %v", summary.Schemas)
+	}
+	if strings.Join(summary.Tables, ",") != "audit.events,public.items" {
+		t.Fatalf("unexpected tables: %v", summary.Tables)
+	}
+	if strings.Join(summary.Extensions, ",") != "pgcrypto" || strings.Join(summary.Indexes, ",") != "public.items_name_idx" {
+		t.Fatalf("unexpected extensions or indexes: %+v", summary)
+	}
+	if strings.Join(summary.Functions, ",") != "public.add(integer, integer)" || summary.Entries != 13 {
+		t.Fatalf("unexpected functions or entry count: %+v", summary)
+	}
+}
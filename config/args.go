@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// managedShortOptions and managedLongOptions are pg_dump/pg_restore options
+// the controller sets itself; extra arguments must not override them.
+var (
+	managedShortOptions = []string{"f", "F", "d"}
+	managedLongOptions  = []string{"file", "format", "dbname"}
+)
+
+// validateExtraArgs checks extra tool arguments. Every argument must be an
+// option; values are given as --option=value or -ovalue so that nothing can
+// be mistaken for a positional archive or database argument.
+func validateExtraArgs(scope string, args []string) []error {
+	var problems []error
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			problems = append(problems, fmt.Errorf("%s: %q is not an option, use --option=value", scope, arg))
+			continue
+		}
+		if managedOption(arg) {
+			problems = append(problems, fmt.Errorf("%s: %q is managed by the controller", scope, arg))
+		}
+	}
+	return problems
+}
+
+func managedOption(arg string) bool {
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		name, _, _ = strings.Cut(name, "=")
+		for _, managed := range managedLongOptions {
+			// getopt accepts unambiguous abbreviations of long options.
+			if strings.HasPrefix(managed, name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, managed := range managedShortOptions {
+		if strings.HasPrefix(arg[1:], managed) {
+			return true
+		}
+	}
+	return false
+}
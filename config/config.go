@@ -0,0 +1,594 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"docker-postgres-backuper/storage"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const BaseBackupDirectoryPath = "/var/lib/postgresql/backup/data"
+
+// BaseSpoolDirectoryPath holds failed uploads in production mode.
+const BaseSpoolDirectoryPath = "/var/lib/postgresql/backup/spool"
+
+// Config is the effective controller configuration. It is assembled from the
+// optional file referenced by CONFIG_FILE and then overridden by environment
+// variables.
+type Config struct {
+	Mode      string     `yaml:"mode"`
+	Storage   Storage    `yaml:"storage"`
+	Discovery Discovery  `yaml:"discovery"`
+	Schedule  Schedule   `yaml:"schedule"`
+	Retention Retention  `yaml:"retention"`
+	Databases []Database `yaml:"databases"`
+	// AuditLog is the JSON lines file that records destructive commands.
+	AuditLog string `yaml:"audit_log"`
+	// MetricsAddress is the listen address of the Prometheus metrics
+	// endpoint served by the scheduler; empty disables it.
+	MetricsAddress string `yaml:"metrics_address"`
+
+	env environment
+}
+
+// Storage selects where backups are kept. Target is a single target or a
+// comma-separated list of targets that every backup is replicated to; besides
+// "local" and "s3" it may name entries of Targets.
+type Storage struct {
+	Target  string                   `yaml:"target"`
+	Local   Local                    `yaml:"local"`
+	S3      S3                       `yaml:"s3"`
+	Targets map[string]StorageTarget `yaml:"targets"`
+	Spool   Spool                    `yaml:"spool"`
+}
+
+// Spool is the local outbox for uploads to S3 that failed. A zero size
+// disables it.
+type Spool struct {
+	Path      string `yaml:"path"`
+	MaxSizeMB int    `yaml:"max_size_mb"`
+}
+
+// StorageTarget is a named storage target. Target is "local",
+// "local:<path>", "s3" or "s3://<bucket>[/<prefix>]" and defaults to the name;
+// Retention overrides the retention of every database on this target.
+type StorageTarget struct {
+	Target    string    `yaml:"target"`
+	Retention Retention `yaml:"retention"`
+}
+
+type Local struct {
+	Path string `yaml:"path"`
+}
+
+type S3 struct {
+	Bucket              string `yaml:"bucket"`
+	Prefix              string `yaml:"prefix"`
+	Region              string `yaml:"region"`
+	Endpoint            string `yaml:"endpoint"`
+	AccessKeyID         string `yaml:"access_key_id"`
+	AccessKeyIDFile     string `yaml:"access_key_id_file"`
+	SecretAccessKey     string `yaml:"secret_access_key"`
+	SecretAccessKeyFile string `yaml:"secret_access_key_file"`
+	UseTLS              bool   `yaml:"use_tls"`
+	ForcePathStyle      bool   `yaml:"force_path_style"`
+}
+
+// Discovery configures automatic discovery of databases from Docker labels.
+type Discovery struct {
+	Enabled  bool          `yaml:"enabled"`
+	Socket   string        `yaml:"socket"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Database describes a single managed PostgreSQL service.
+type Database struct {
+	Name         string    `yaml:"name"`
+	Host         string    `yaml:"host"`
+	User         string    `yaml:"user"`
+	Password     string    `yaml:"password"`
+	PasswordFile string    `yaml:"password_file"`
+	Database     string    `yaml:"database"`
+	Port         int       `yaml:"port"`
+	SSLMode      string    `yaml:"sslmode"`
+	SSLRootCert  string    `yaml:"sslrootcert"`
+	SSLCert      string    `yaml:"sslcert"`
+	SSLKey       string    `yaml:"sslkey"`
+	AppName      string    `yaml:"application_name"`
+	Timeout      int       `yaml:"connect_timeout"`
+	URL          string    `yaml:"url"`
+	Cluster      Cluster   `yaml:"cluster"`
+	Globals      Globals   `yaml:"globals"`
+	Filters      Filters   `yaml:"filters"`
+	DumpArgs     []string  `yaml:"pg_dump_args"`
+	RestoreArgs  []string  `yaml:"pg_restore_args"`
+	Protected    bool      `yaml:"protected"`
+	Schedule     Schedule  `yaml:"schedule"`
+	Retention    Retention `yaml:"retention"`
+}
+
+// Cluster enables dumping every logical database of the server. Include and
+// exclude hold shell patterns matched against database names.
+type Cluster struct {
+	Enabled bool     `yaml:"enabled"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Globals enables a pg_dumpall --globals-only dump of roles and tablespaces
+// next to every database dump.
+type Globals struct {
+	Enabled         bool `yaml:"enabled"`
+	NoRolePasswords bool `yaml:"no_role_passwords"`
+}
+
+// Filters restrict what pg_dump includes. Patterns use pg_dump syntax.
+type Filters struct {
+	IncludeSchemas   []string `yaml:"include_schemas"`
+	ExcludeSchemas   []string `yaml:"exclude_schemas"`
+	IncludeTables    []string `yaml:"include_tables"`
+	ExcludeTables    []string `yaml:"exclude_tables"`
+	ExcludeTableData []string `yaml:"exclude_table_data"`
+	SchemaOnly       bool     `yaml:"schema_only"`
+}
+
+// Args returns the pg_dump arguments for the include and exclude lists.
+func (f Filters) Args() []string {
+	var args []string
+	for _, schema := range f.IncludeSchemas {
+		args = append(args, "--schema="+schema)
+	}
+	for _, schema := range f.ExcludeSchemas {
+		args = append(args, "--exclude-schema="+schema)
+	}
+	for _, table := range f.IncludeTables {
+		args = append(args, "--table="+table)
+	}
+	for _, table := range f.ExcludeTables {
+		args = append(args, "--exclude-table="+table)
+	}
+	for _, table := range f.ExcludeTableData {
+		args = append(args, "--exclude-table-data="+table)
+	}
+	return args
+}
+
+// Schedule lists the hours of the day at which scheduled dumps run.
+type Schedule struct {
+	Hours []int `yaml:"hours"`
+}
+
+// Retention holds the number of days each backup type is kept. Zero values
+// inherit the global setting.
+type Retention struct {
+	Daily      int `yaml:"daily"`
+	Weekly     int `yaml:"weekly"`
+	Monthly    int `yaml:"monthly"`
+	Manual     int `yaml:"manual"`
+	Schema     int `yaml:"schema"`
+	PreRestore int `yaml:"pre_restore"`
+}
+
+// Load reads the configuration file (when CONFIG_FILE is set), applies
+// environment overrides (including ENV_FILE), resolves secrets and validates
+// the result.
+func Load() (*Config, error) {
+	cfg, problems := Resolve()
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return cfg, nil
+}
+
+// Resolve builds the effective configuration and returns every problem found
+// on the way instead of stopping at the first one. The configuration is
+// returned even when problems exist so it can still be reported; it is nil
+// only when the configuration file cannot be parsed.
+func Resolve() (*Config, []error) {
+	env, err := loadEnvironment()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	cfg := defaults()
+	cfg.env = env
+
+	if path := env.get("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, []error{err}
+		}
+	}
+
+	var problems []error
+	problems = append(problems, unwrapJoined(env.applyEnv(cfg))...)
+	problems = append(problems, cfg.resolve()...)
+	problems = append(problems, unwrapJoined(cfg.Validate())...)
+	return cfg, problems
+}
+
+func defaults() *Config {
+	return &Config{
+		Storage: Storage{
+			S3:    S3{UseTLS: true},
+			Spool: Spool{MaxSizeMB: 10240},
+		},
+		Discovery: Discovery{
+			Socket:   "/var/run/docker.sock",
+			Interval: 5 * time.Minute,
+		},
+		Schedule: Schedule{Hours: []int{3, 9, 15, 21}},
+		Retention: Retention{
+			Daily:      7,
+			Weekly:     30,
+			Monthly:    365,
+			Manual:     365,
+			Schema:     30,
+			PreRestore: 7,
+		},
+	}
+}
+
+// loadFile decodes a YAML or JSON file into cfg. JSON is accepted because it
+// is a subset of YAML.
+func loadFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) resolve() []error {
+	var problems []error
+
+	if c.Storage.Local.Path == "" {
+		c.Storage.Local.Path = "backup-data"
+		if c.Production() {
+			c.Storage.Local.Path = BaseBackupDirectoryPath
+		}
+	}
+	if c.Storage.Spool.Path == "" {
+		c.Storage.Spool.Path = "backup-spool"
+		if c.Production() {
+			c.Storage.Spool.Path = BaseSpoolDirectoryPath
+		}
+	}
+	if c.AuditLog == "" {
+		c.AuditLog = filepath.Join(c.Storage.Local.Path, "audit.log")
+	}
+
+	accessKeyID, err := readSecret(c.Storage.S3.AccessKeyID, c.Storage.S3.AccessKeyIDFile)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("read s3 access key id: %w", err))
+	}
+	c.Storage.S3.AccessKeyID = accessKeyID
+	secretAccessKey, err := readSecret(c.Storage.S3.SecretAccessKey, c.Storage.S3.SecretAccessKeyFile)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("read s3 secret access key: %w", err))
+	}
+	c.Storage.S3.SecretAccessKey = secretAccessKey
+
+	for i := range c.Databases {
+		if err := c.resolveDatabase(&c.Databases[i]); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
+
+func (c *Config) resolveDatabase(db *Database) error {
+	if db.Host == "" {
+		db.Host = db.Name
+	}
+	if db.User == "" {
+		db.User = "postgres"
+	}
+	if db.Database == "" {
+		db.Database = "postgres"
+	}
+	if len(db.Schedule.Hours) == 0 {
+		db.Schedule.Hours = slices.Clone(c.Schedule.Hours)
+	}
+	db.Retention = db.Retention.inherit(c.Retention)
+
+	password, err := readSecret(db.Password, db.PasswordFile)
+	if err != nil {
+		return fmt.Errorf("%s: read password: %w", db.Name, err)
+	}
+	if password == "" {
+		password = "postgres"
+	}
+	db.Password = password
+	return nil
+}
+
+// Validate reports every problem found in the configuration.
+func (c *Config) Validate() error {
+	var problems []error
+
+	seenTargets := map[string]bool{}
+	for _, name := range strings.Split(c.Storage.Target, ",") {
+		name = strings.TrimSpace(name)
+		if seenTargets[name] {
+			problems = append(problems, fmt.Errorf("backup target %s is listed more than once", name))
+			continue
+		}
+		seenTargets[name] = true
+		problems = append(problems, c.validateTarget(name)...)
+	}
+	for name, target := range c.Storage.Targets {
+		problems = append(problems, target.Retention.validate("storage target "+name+": retention", true)...)
+	}
+	if c.Storage.Spool.MaxSizeMB < 0 {
+		problems = append(problems, errors.New("spool: max size must not be negative"))
+	}
+
+	if c.Discovery.Enabled && c.Discovery.Interval <= 0 {
+		problems = append(problems, errors.New("discovery: interval must be positive"))
+	}
+
+	problems = append(problems, c.Schedule.validate("schedule")...)
+	problems = append(problems, c.Retention.validate("retention", false)...)
+
+	seen := map[string]bool{}
+	for _, db := range c.Databases {
+		if db.Name == "" {
+			problems = append(problems, errors.New("database entry without name"))
+			continue
+		}
+		if seen[db.Name] {
+			problems = append(problems, fmt.Errorf("database %s is defined more than once", db.Name))
+		}
+		seen[db.Name] = true
+		problems = append(problems, db.validateConnection()...)
+		problems = append(problems, db.Cluster.validate(db.Name+": cluster")...)
+		problems = append(problems, validateExtraArgs(db.Name+": pg_dump_args", db.DumpArgs)...)
+		problems = append(problems, validateExtraArgs(db.Name+": pg_restore_args", db.RestoreArgs)...)
+		problems = append(problems, db.Schedule.validate(db.Name+": schedule")...)
+		problems = append(problems, db.Retention.validate(db.Name+": retention", false)...)
+	}
+
+	return errors.Join(problems...)
+}
+
+// validateTarget checks the settings a backup target depends on.
+func (c *Config) validateTarget(name string) []error {
+	var problems []error
+	spec := name
+	if target, ok := c.Storage.Targets[name]; ok && target.Target != "" {
+		spec = target.Target
+	}
+
+	switch {
+	case spec == "" || spec == "local":
+		if c.Storage.Local.Path == "" {
+			problems = append(problems, errors.New("local storage requires path"))
+		}
+	case strings.HasPrefix(spec, "local:"):
+		if spec == "local:" {
+			problems = append(problems, fmt.Errorf("storage target %s: local storage requires path", name))
+		}
+	case spec == "s3" || strings.HasPrefix(spec, "s3://"):
+		s3 := c.Storage.S3
+		if spec == "s3" && s3.Bucket == "" || spec == "s3://" {
+			problems = append(problems, errors.New("s3 storage requires bucket"))
+		}
+		if s3.Region == "" {
+			problems = append(problems, errors.New("s3 storage requires region"))
+		}
+		if s3.Endpoint == "" {
+			problems = append(problems, errors.New("s3 storage requires endpoint"))
+		}
+		if s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			problems = append(problems, errors.New("s3 storage requires access key credentials"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("unsupported backup target: %s", spec))
+	}
+	return problems
+}
+
+// Production reports whether the controller runs in production mode.
+func (c *Config) Production() bool {
+	return c.Mode == "production"
+}
+
+// Database returns the configured database with the given name. Names that
+// are not configured resolve from environment variables and defaults so
+// manual commands keep working for ad-hoc services.
+func (c *Config) Database(name string) (Database, error) {
+	for _, db := range c.Databases {
+		if db.Name == name {
+			return db, nil
+		}
+	}
+	db := Database{Name: name}
+	if err := c.env.applyDatabaseEnv(&db); err != nil {
+		return Database{}, err
+	}
+	if err := c.resolveDatabase(&db); err != nil {
+		return Database{}, err
+	}
+	return db, nil
+}
+
+// WithDatabases returns a copy of the configuration extended with additional
+// databases, for example discovered ones. Databases that are already
+// configured keep their configured settings. Entries that cannot be resolved
+// are skipped and reported.
+func (c *Config) WithDatabases(databases []Database) (*Config, []error) {
+	merged := *c
+	merged.Databases = slices.Clone(c.Databases)
+
+	var problems []error
+	for _, db := range databases {
+		if hasDatabase(merged.Databases, db.Name) {
+			continue
+		}
+		if err := c.env.applyDatabaseEnv(&db); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if err := c.resolveDatabase(&db); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if errs := db.Schedule.validate(db.Name + ": schedule"); len(errs) > 0 {
+			problems = append(problems, errs...)
+			continue
+		}
+		merged.Databases = append(merged.Databases, db)
+	}
+	return &merged, problems
+}
+
+// StorageConfig converts the storage section into provider configuration.
+func (c *Config) StorageConfig() storage.Config {
+	cfg := storage.Config{
+		Local: storage.LocalConfig{BasePath: c.Storage.Local.Path},
+		S3: storage.S3Config{
+			Bucket:          c.Storage.S3.Bucket,
+			Prefix:          c.Storage.S3.Prefix,
+			Region:          c.Storage.S3.Region,
+			Endpoint:        c.Storage.S3.Endpoint,
+			AccessKeyID:     c.Storage.S3.AccessKeyID,
+			SecretAccessKey: c.Storage.S3.SecretAccessKey,
+			UseTLS:          c.Storage.S3.UseTLS,
+			ForcePathStyle:  c.Storage.S3.ForcePathStyle,
+		},
+	}
+	cfg.Spool = storage.SpoolConfig{Path: c.Storage.Spool.Path, MaxBytes: int64(c.Storage.Spool.MaxSizeMB) << 20}
+	for name, target := range c.Storage.Targets {
+		if cfg.Targets == nil {
+			cfg.Targets = map[string]storage.TargetConfig{}
+		}
+		cfg.Targets[name] = storage.TargetConfig{Spec: target.Target, Retention: target.Retention.Policy()}
+	}
+	return cfg
+}
+
+// Matches reports whether a logical database is selected by the include and
+// exclude patterns. An empty include list selects every database.
+func (c Cluster) Matches(name string) bool {
+	included := len(c.Include) == 0
+	for _, pattern := range c.Include {
+		if ok, _ := path.Match(pattern, name); ok {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pattern := range c.Exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Cluster) validate(scope string) []error {
+	var problems []error
+	for _, pattern := range slices.Concat(c.Include, c.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			problems = append(problems, fmt.Errorf("%s: invalid pattern %q", scope, pattern))
+		}
+	}
+	return problems
+}
+
+// Due reports whether a scheduled dump should run at the given time.
+func (s Schedule) Due(t time.Time) bool {
+	return slices.Contains(s.Hours, t.Hour())
+}
+
+func (s Schedule) validate(scope string) []error {
+	var problems []error
+	for _, hour := range s.Hours {
+		if hour < 0 || hour > 23 {
+			problems = append(problems, fmt.Errorf("%s: hour %d is out of range 0-23", scope, hour))
+		}
+	}
+	return problems
+}
+
+// Policy converts retention days into the storage retention policy.
+func (r Retention) Policy() storage.RetentionPolicy {
+	day := 24 * time.Hour
+	return storage.RetentionPolicy{
+		Daily:      time.Duration(r.Daily) * day,
+		Weekly:     time.Duration(r.Weekly) * day,
+		Monthly:    time.Duration(r.Monthly) * day,
+		Manual:     time.Duration(r.Manual) * day,
+		Schema:     time.Duration(r.Schema) * day,
+		PreRestore: time.Duration(r.PreRestore) * day,
+	}
+}
+
+func (r Retention) inherit(parent Retention) Retention {
+	if r.Daily == 0 {
+		r.Daily = parent.Daily
+	}
+	if r.Weekly == 0 {
+		r.Weekly = parent.Weekly
+	}
+	if r.Monthly == 0 {
+		r.Monthly = parent.Monthly
+	}
+	if r.Manual == 0 {
+		r.Manual = parent.Manual
+	}
+	if r.Schema == 0 {
+		r.Schema = parent.Schema
+	}
+	if r.PreRestore == 0 {
+		r.PreRestore = parent.PreRestore
+	}
+	return r
+}
+
+// validate checks retention days. Zero is only accepted where it inherits
+// another setting; as an effective policy it would expire every backup of
+// the type at the next cleanup.
+func (r Retention) validate(scope string, inherits bool) []error {
+	var problems []error
+	values := []struct {
+		name string
+		days int
+	}{{"daily", r.Daily}, {"weekly", r.Weekly}, {"monthly", r.Monthly}, {"manual", r.Manual}, {"schema", r.Schema}, {"pre-restore", r.PreRestore}}
+	for _, value := range values {
+		switch {
+		case value.days < 0:
+			problems = append(problems, fmt.Errorf("%s: %s days must not be negative", scope, value.name))
+		case value.days == 0 && !inherits:
+			problems = append(problems, fmt.Errorf("%s: %s days must be positive", scope, value.name))
+		}
+	}
+	return problems
+}
+
+func unwrapJoined(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
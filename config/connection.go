@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ConnectionEnv returns the libpq environment variables for the database.
+// Values given in URL take precedence over these variables.
+func (d Database) ConnectionEnv() []string {
+	env := []string{"PGPASSWORD=" + d.Password}
+	add := func(key, value string) {
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	add("PGHOST", d.Host)
+	add("PGUSER", d.User)
+	add("PGDATABASE", d.Database)
+	if d.Port != 0 {
+		add("PGPORT", strconv.Itoa(d.Port))
+	}
+	add("PGSSLMODE", d.SSLMode)
+	add("PGSSLROOTCERT", d.SSLRootCert)
+	add("PGSSLCERT", d.SSLCert)
+	add("PGSSLKEY", d.SSLKey)
+	add("PGAPPNAME", d.AppName)
+	if d.Timeout != 0 {
+		add("PGCONNECT_TIMEOUT", strconv.Itoa(d.Timeout))
+	}
+	return env
+}
+
+// ForDatabase returns a copy connected to another logical database on the
+// same server.
+func (d Database) ForDatabase(name string) Database {
+	d.Database = name
+	if d.URL != "" {
+		if parsed, err := url.Parse(d.URL); err == nil {
+			parsed.Path = "/" + name
+			parsed.RawPath = ""
+			d.URL = parsed.String()
+		}
+	}
+	return d
+}
+
+// Target resolves the target of a clone restore: a postgres:// URL, a
+// configured database, or "<database>/<dbname>" for another logical database
+// on the server of a configured database.
+func (c *Config) Target(value string) (Database, error) {
+	if strings.HasPrefix(value, "postgres://") || strings.HasPrefix(value, "postgresql://") {
+		target := Database{Name: redactURL(value), URL: value}
+		if problems := target.validateConnection(); len(problems) > 0 {
+			return Database{}, problems[0]
+		}
+		parsed, _ := url.Parse(value)
+		target.Database = strings.TrimPrefix(parsed.Path, "/")
+		if target.Database == "" {
+			return Database{}, fmt.Errorf("%s: database url has no database name", target.Name)
+		}
+		return target, nil
+	}
+
+	name, dbname, nested := strings.Cut(value, "/")
+	target, err := c.Database(name)
+	if err != nil {
+		return Database{}, err
+	}
+	if nested {
+		if dbname == "" {
+			return Database{}, fmt.Errorf("%s: missing database name", value)
+		}
+		target = target.ForDatabase(dbname)
+		target.Name = value
+	}
+	return target, nil
+}
+
+// DBName is the value passed to the client tools' -d option: the full URL
+// when one is configured, otherwise the database name.
+func (d Database) DBName() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return d.Database
+}
+
+func (d Database) validateConnection() []error {
+	var problems []error
+	if d.Port < 0 || d.Port > 65535 {
+		problems = append(problems, fmt.Errorf("%s: port %d is out of range", d.Name, d.Port))
+	}
+	if d.SSLMode != "" && !slices.Contains(sslModes, d.SSLMode) {
+		problems = append(problems, fmt.Errorf("%s: unsupported sslmode %q", d.Name, d.SSLMode))
+	}
+	if d.Timeout < 0 {
+		problems = append(problems, fmt.Errorf("%s: connect_timeout must not be negative", d.Name))
+	}
+	if d.URL != "" {
+		parsed, err := url.Parse(d.URL)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: invalid database url", d.Name))
+		} else if parsed.Scheme != "postgres" && parsed.Scheme != "postgresql" {
+			problems = append(problems, fmt.Errorf("%s: database url must use the postgres:// scheme", d.Name))
+		}
+	}
+	return problems
+}
+
+// redactURL hides the passwords of a connection URL.
+func redactURL(value string) string {
+	return replacePassword(value, redactedValue)
+}
+
+// PlaceholderURL returns the connection URL with its password replaced by
+// placeholder, for commands printed for the user to run.
+func (d Database) PlaceholderURL(placeholder string) string {
+	return replacePassword(d.URL, placeholder)
+}
+
+// replacePassword replaces the password in the user part of a connection URL
+// and the password and sslpassword query parameters.
+func replacePassword(value, replacement string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return redact(value)
+	}
+
+	redacted := false
+	if parsed.RawQuery != "" {
+		params := strings.Split(parsed.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err == nil && (name == "password" || name == "sslpassword") {
+				params[i] = key + "=" + replacement
+				redacted = true
+			}
+		}
+		parsed.RawQuery = strings.Join(params, "&")
+	}
+
+	if _, ok := parsed.User.Password(); !ok {
+		if !redacted {
+			return value
+		}
+		return parsed.String()
+	}
+	username := parsed.User.Username()
+	parsed.User = nil
+	return strings.Replace(parsed.String(), "://", "://"+url.PathEscape(username)+":"+replacement+"@", 1)
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// environment resolves variables from the process environment, overridden by
+// the KEY=VALUE file referenced by ENV_FILE. The file is re-read on every load
+// so settings kept there can change without restarting the process.
+type environment struct {
+	overrides map[string]string
+}
+
+func loadEnvironment() (environment, error) {
+	env := environment{overrides: map[string]string{}}
+	path := os.Getenv("ENV_FILE")
+	if path == "" {
+		return env, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return env, fmt.Errorf("open env file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return env, fmt.Errorf("env file %s: invalid line %q", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env.overrides[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return env, fmt.Errorf("read env file %s: %w", path, err)
+	}
+	return env, nil
+}
+
+func (e environment) get(key string) string {
+	if value, ok := e.overrides[key]; ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// applyEnv overrides file values with the environment variables documented
+// in the README. Databases named in DATABASE_LIST that are missing from the
+// file are appended.
+func (e environment) applyEnv(cfg *Config) error {
+	var problems []error
+
+	e.setString(&cfg.Mode, "MODE")
+	e.setString(&cfg.Storage.Target, "BACKUP_TARGET")
+	e.setString(&cfg.AuditLog, "AUDIT_LOG")
+	e.setString(&cfg.MetricsAddress, "METRICS_ADDR")
+	e.setString(&cfg.Storage.Spool.Path, "SPOOL_DIR")
+	problems = append(problems, e.setInt(&cfg.Storage.Spool.MaxSizeMB, "SPOOL_MAX_SIZE_MB"))
+	for _, name := range strings.Split(cfg.Storage.Target, ",") {
+		problems = append(problems, e.applyTargetEnv(&cfg.Storage, strings.TrimSpace(name)))
+	}
+
+	s3 := &cfg.Storage.S3
+	e.setString(&s3.Bucket, "S3_BUCKET")
+	e.setString(&s3.Prefix, "S3_PREFIX")
+	e.setString(&s3.Region, "S3_REGION")
+	e.setString(&s3.Endpoint, "S3_ENDPOINT")
+	e.setString(&s3.AccessKeyID, "S3_ACCESS_KEY_ID")
+	e.setString(&s3.AccessKeyIDFile, "S3_ACCESS_KEY_ID_FILE")
+	e.setString(&s3.SecretAccessKey, "S3_SECRET_ACCESS_KEY")
+	e.setString(&s3.SecretAccessKeyFile, "S3_SECRET_ACCESS_KEY_FILE")
+	problems = append(problems, e.setBool(&s3.UseTLS, "S3_USE_TLS"))
+	problems = append(problems, e.setBool(&s3.ForcePathStyle, "S3_FORCE_PATH_STYLE"))
+
+	problems = append(problems, e.setBool(&cfg.Discovery.Enabled, "DOCKER_DISCOVERY"))
+	e.setString(&cfg.Discovery.Socket, "DOCKER_SOCKET")
+	problems = append(problems, e.setDuration(&cfg.Discovery.Interval, "DOCKER_DISCOVERY_INTERVAL"))
+
+	problems = append(problems, e.setHours(&cfg.Schedule.Hours, "SCHEDULE_HOURS"))
+	problems = append(problems, e.setRetention(&cfg.Retention, "RETENTION"))
+
+	if list := e.get("DATABASE_LIST"); list != "" {
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || hasDatabase(cfg.Databases, name) {
+				continue
+			}
+			cfg.Databases = append(cfg.Databases, Database{Name: name})
+		}
+	}
+
+	for i := range cfg.Databases {
+		problems = append(problems, e.applyDatabaseEnv(&cfg.Databases[i]))
+	}
+
+	return errors.Join(problems...)
+}
+
+// applyTargetEnv reads the TARGET_<NAME>_RETENTION_* variables of a backup
+// target.
+func (e environment) applyTargetEnv(s *Storage, name string) error {
+	if name == "" {
+		return nil
+	}
+	target := s.Targets[name]
+	if err := e.setRetention(&target.Retention, databaseEnvKey("TARGET_"+name, "RETENTION")); err != nil {
+		return err
+	}
+	if target != s.Targets[name] {
+		if s.Targets == nil {
+			s.Targets = map[string]StorageTarget{}
+		}
+		s.Targets[name] = target
+	}
+	return nil
+}
+
+// applyDatabaseEnv overrides database values with <SERVICE>_* variables.
+func (e environment) applyDatabaseEnv(db *Database) error {
+	e.setString(&db.Host, databaseEnvKey(db.Name, "POSTGRES_HOST"))
+	e.setString(&db.User, databaseEnvKey(db.Name, "POSTGRES_USER"))
+	e.setString(&db.Password, databaseEnvKey(db.Name, "POSTGRES_PASSWORD"))
+	e.setString(&db.PasswordFile, databaseEnvKey(db.Name, "POSTGRES_PASSWORD_FILE"))
+	e.setString(&db.Database, databaseEnvKey(db.Name, "POSTGRES_DB"))
+	e.setString(&db.SSLMode, databaseEnvKey(db.Name, "POSTGRES_SSLMODE"))
+	e.setString(&db.SSLRootCert, databaseEnvKey(db.Name, "POSTGRES_SSLROOTCERT"))
+	e.setString(&db.SSLCert, databaseEnvKey(db.Name, "POSTGRES_SSLCERT"))
+	e.setString(&db.SSLKey, databaseEnvKey(db.Name, "POSTGRES_SSLKEY"))
+	e.setString(&db.AppName, databaseEnvKey(db.Name, "POSTGRES_APPLICATION_NAME"))
+	e.setString(&db.URL, databaseEnvKey(db.Name, "DATABASE_URL"))
+	e.setList(&db.Cluster.Include, databaseEnvKey(db.Name, "CLUSTER_INCLUDE"))
+	e.setList(&db.Cluster.Exclude, databaseEnvKey(db.Name, "CLUSTER_EXCLUDE"))
+	e.setList(&db.Filters.IncludeSchemas, databaseEnvKey(db.Name, "INCLUDE_SCHEMAS"))
+	e.setList(&db.Filters.ExcludeSchemas, databaseEnvKey(db.Name, "EXCLUDE_SCHEMAS"))
+	e.setList(&db.Filters.IncludeTables, databaseEnvKey(db.Name, "INCLUDE_TABLES"))
+	e.setList(&db.Filters.ExcludeTables, databaseEnvKey(db.Name, "EXCLUDE_TABLES"))
+	e.setList(&db.Filters.ExcludeTableData, databaseEnvKey(db.Name, "EXCLUDE_TABLE_DATA"))
+	e.setFields(&db.DumpArgs, databaseEnvKey(db.Name, "PG_DUMP_ARGS"))
+	e.setFields(&db.RestoreArgs, databaseEnvKey(db.Name, "PG_RESTORE_ARGS"))
+
+	return errors.Join(
+		e.setBool(&db.Cluster.Enabled, databaseEnvKey(db.Name, "CLUSTER")),
+		e.setBool(&db.Globals.Enabled, databaseEnvKey(db.Name, "DUMP_GLOBALS")),
+		e.setBool(&db.Globals.NoRolePasswords, databaseEnvKey(db.Name, "GLOBALS_NO_ROLE_PASSWORDS")),
+		e.setBool(&db.Filters.SchemaOnly, databaseEnvKey(db.Name, "SCHEMA_ONLY")),
+		e.setBool(&db.Protected, databaseEnvKey(db.Name, "PROTECTED")),
+		e.setInt(&db.Port, databaseEnvKey(db.Name, "POSTGRES_PORT")),
+		e.setInt(&db.Timeout, databaseEnvKey(db.Name, "POSTGRES_CONNECT_TIMEOUT")),
+		e.setHours(&db.Schedule.Hours, databaseEnvKey(db.Name, "SCHEDULE_HOURS")),
+		e.setRetention(&db.Retention, databaseEnvKey(db.Name, "RETENTION")),
+	)
+}
+
+func databaseEnvKey(database, env string) string {
+	return strings.ToUpper(strings.ReplaceAll(database, "-", "_")) + "_" + env
+}
+
+func hasDatabase(databases []Database, name string) bool {
+	for _, db := range databases {
+		if db.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readSecret returns the trimmed content of path when it is set and value
+// otherwise, so secret files always win over inline values.
+func readSecret(value, path string) (string, error) {
+	if path == "" {
+		return value, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file %s: %w", path, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func (e environment) setString(target *string, key string) {
+	if value := e.get(key); value != "" {
+		*target = value
+	}
+}
+
+func (e environment) setList(target *[]string, key string) {
+	value := e.get(key)
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func (e environment) setFields(target *[]string, key string) {
+	if value := e.get(key); value != "" {
+		*target = strings.Fields(value)
+	}
+}
+
+func (e environment) setBool(target *bool, key string) error {
+	value := e.get(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid boolean %q", key, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func (e environment) setInt(target *int, key string) error {
+	value := e.get(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid number %q", key, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func (e environment) setHours(target *[]int, key string) error {
+	value := e.get(key)
+	if value == "" {
+		return nil
+	}
+	hours, err := ParseHours(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*target = hours
+	return nil
+}
+
+// ParseHours parses a comma-separated list of hours such as "3,9,15,21".
+func ParseHours(value string) ([]int, error) {
+	var hours []int
+	for _, part := range strings.Split(value, ",") {
+		hour, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hour %q", part)
+		}
+		hours = append(hours, hour)
+	}
+	return hours, nil
+}
+
+func (e environment) setDuration(target *time.Duration, key string) error {
+	value := e.get(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid duration %q", key, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func (e environment) setRetention(target *Retention, prefix string) error {
+	return errors.Join(
+		e.setInt(&target.Daily, prefix+"_DAILY_DAYS"),
+		e.setInt(&target.Weekly, prefix+"_WEEKLY_DAYS"),
+		e.setInt(&target.Monthly, prefix+"_MONTHLY_DAYS"),
+		e.setInt(&target.Manual, prefix+"_MANUAL_DAYS"),
+		e.setInt(&target.Schema, prefix+"_SCHEMA_DAYS"),
+		e.setInt(&target.PreRestore, prefix+"_PRE_RESTORE_DAYS"),
+	)
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"slices"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const redactedValue = "<redacted>"
+
+// Redacted returns a copy of the configuration with passwords and S3
+// credentials replaced, suitable for printing.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Storage.S3.AccessKeyID = redact(c.Storage.S3.AccessKeyID)
+	redacted.Storage.S3.SecretAccessKey = redact(c.Storage.S3.SecretAccessKey)
+	redacted.Databases = slices.Clone(c.Databases)
+	for i := range redacted.Databases {
+		redacted.Databases[i].Password = redact(redacted.Databases[i].Password)
+		if redacted.Databases[i].URL != "" {
+			redacted.Databases[i].URL = redactURL(redacted.Databases[i].URL)
+		}
+	}
+	return &redacted
+}
+
+// Marshal renders the configuration in the configuration file format.
+func (c *Config) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// runConfigCommand implements `config validate` and `config print`.
+func runConfigCommand(args []string) {
+	if len(args) == 0 || (args[0] != "validate" && args[0] != "print") {
+		panic("uncorrected command")
+	}
+
+	cfg, problems := config.Resolve()
+	if cfg != nil {
+		if _, err := storage.NewProvider(cfg.Storage.Target, cfg.StorageConfig()); err != nil && !reported(problems, err) {
+			problems = append(problems, fmt.Errorf("storage: %w", err))
+		}
+	}
+
+	if args[0] == "print" && cfg != nil {
+		output, err := cfg.Redacted().Marshal()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Print(string(output))
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "configuration has %d problem(s):\n", len(problems))
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, "  -", problem)
+		}
+		os.Exit(1)
+	}
+
+	if args[0] == "validate" {
+		fmt.Printf("configuration is valid: %d database(s), %s storage\n", len(cfg.Databases), targetName(cfg.Storage.Target))
+	}
+}
+
+func targetName(target string) string {
+	if target == "" {
+		return "local"
+	}
+	return target
+}
+
+func reported(problems []error, err error) bool {
+	for _, problem := range problems {
+		if problem.Error() == err.Error() {
+			return true
+		}
+	}
+	return false
+}
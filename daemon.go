@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// daemonState is the configuration and provider used by scheduled runs. It
+// is replaced as a whole on reload, so a run always sees a consistent pair.
+// base is the loaded configuration and cfg additionally holds discovered
+// databases.
+type daemonState struct {
+	base     *config.Config
+	cfg      *config.Config
+	provider storage.Provider
+}
+
+type daemon struct {
+	state       atomic.Pointer[daemonState]
+	updating    sync.Mutex
+	dumping     sync.Mutex
+	flushing    sync.Mutex
+	discovering sync.Mutex
+}
+
+func newDaemon(cfg *config.Config, provider storage.Provider) *daemon {
+	d := &daemon{}
+	discovered, err := withDiscoveredDatabases(cfg)
+	if err != nil {
+		fmt.Println(err)
+	}
+	d.state.Store(&daemonState{base: cfg, cfg: discovered, provider: provider})
+	return d
+}
+
+// databases returns every database the daemon currently manages.
+func (d *daemon) databases() []config.Database {
+	return d.state.Load().cfg.Databases
+}
+
+// run performs scheduled dumps every hour, refreshes discovered databases,
+// retries spooled uploads every minute and reloads the configuration on
+// SIGHUP. It never returns.
+func (d *daemon) run() {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	discoveryTicker := time.NewTicker(d.discoveryInterval())
+	defer discoveryTicker.Stop()
+
+	flushTicker := time.NewTicker(time.Minute)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-reload:
+			if err := d.reload(); err != nil {
+				fmt.Println("reload configuration error, keeping previous configuration:", err)
+				continue
+			}
+			discoveryTicker.Reset(d.discoveryInterval())
+			fmt.Println("configuration reloaded")
+		case <-discoveryTicker.C:
+			go d.discover()
+		case now := <-flushTicker.C:
+			go d.flush(now)
+		case now := <-ticker.C:
+			d.tick(now)
+		}
+	}
+}
+
+func (d *daemon) discoveryInterval() time.Duration {
+	if interval := d.state.Load().base.Discovery.Interval; interval > 0 {
+		return interval
+	}
+	return 5 * time.Minute
+}
+
+// discover refreshes the databases found through Docker labels. When Docker
+// cannot be reached the previously discovered databases are kept. A discovery
+// that is still running is not started again, and its result is dropped when
+// the configuration was reloaded in the meantime.
+func (d *daemon) discover() {
+	if !d.discovering.TryLock() {
+		return
+	}
+	defer d.discovering.Unlock()
+
+	state := d.state.Load()
+	if !state.base.Discovery.Enabled {
+		return
+	}
+	cfg, err := withDiscoveredDatabases(state.base)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	d.updating.Lock()
+	defer d.updating.Unlock()
+	if d.state.Load() != state {
+		return
+	}
+	utils.Initialize(state.provider, cfg.Databases)
+	d.state.Store(&daemonState{base: state.base, cfg: cfg, provider: state.provider})
+}
+
+// reload reads and validates the configuration and swaps it in only when it
+// is usable. Dumps that are already running keep their previous state.
+func (d *daemon) reload() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	provider, err := storage.NewProvider(cfg.Storage.Target, cfg.StorageConfig())
+	if err != nil {
+		return err
+	}
+	discovered, err := withDiscoveredDatabases(cfg)
+	if err != nil {
+		fmt.Println(err)
+	}
+	state := &daemonState{base: cfg, cfg: discovered, provider: provider}
+	d.updating.Lock()
+	defer d.updating.Unlock()
+	utils.Initialize(provider, state.cfg.Databases)
+	d.state.Store(state)
+	return nil
+}
+
+// flush retries the uploads waiting in the spools of the current provider.
+// A flush that is still running is not started again.
+func (d *daemon) flush(now time.Time) {
+	if !d.flushing.TryLock() {
+		return
+	}
+	defer d.flushing.Unlock()
+
+	for _, spool := range storage.Spools(d.state.Load().provider) {
+		flushed, err := spool.Flush(now)
+		if flushed > 0 {
+			fmt.Printf("uploaded %d spooled file(s) to %s\n", flushed, spool.Name)
+		}
+		if err != nil {
+			fmt.Println("spool upload error:", err)
+		}
+	}
+}
+
+func (d *daemon) tick(now time.Time) {
+	state := d.state.Load()
+	if !state.cfg.Production() {
+		return
+	}
+
+	var due []config.Database
+	for _, database := range state.cfg.Databases {
+		if database.Schedule.Due(now) {
+			due = append(due, database)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	go func() {
+		d.dumping.Lock()
+		defer d.dumping.Unlock()
+		utils.Dump(state.provider, due, utils.GetBackupType())
+	}()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+func TestDaemonReloadSwapsConfiguration(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "backuper.env")
+	writeFile(t, envFile, "DATABASE_LIST=users\n")
+	t.Setenv("ENV_FILE", envFile)
+	t.Chdir(t.TempDir())
+
+	d := newTestDaemon(t)
+
+	writeFile(t, envFile, "DATABASE_LIST=users,content\n")
+	if err := d.reload(); err != nil {
+		t.Fatalf("reload returned error: %v", err)
+	}
+	if databases := d.state.Load().cfg.Databases; len(databases) != 2 {
+		t.Fatalf("expected 2 databases after reload, got %d", len(databases))
+	}
+}
+
+func TestDaemonReloadKeepsPreviousConfigurationOnError(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "backuper.env")
+	writeFile(t, envFile, "DATABASE_LIST=users\n")
+	t.Setenv("ENV_FILE", envFile)
+	t.Chdir(t.TempDir())
+
+	d := newTestDaemon(t)
+	previous := d.state.Load()
+
+	writeFile(t, envFile, "DATABASE_LIST=users,content\nBACKUP_TARGET=s4\n")
+	if err := d.reload(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if d.state.Load() != previous {
+		t.Fatal("expected previous configuration to be kept")
+	}
+}
+
+func newTestDaemon(t *testing.T) *daemon {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load configuration: %v", err)
+	}
+	provider, err := storage.NewProvider(cfg.Storage.Target, cfg.StorageConfig())
+	if err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	return newDaemon(cfg, provider)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
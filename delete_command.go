@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runDeleteCommand implements `delete <database> <backup-file> [--yes] [--force]`.
+// The deletion is confirmed interactively unless --yes is given and recorded
+// in the audit log. Pinned backups are only deleted with --force.
+func runDeleteCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "delete without asking for confirmation")
+	force := fs.Bool("force", false, "delete the backup even if it is pinned")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		panic("uncorrected command")
+	}
+	database := selectDatabases(cfg, positional[0])[0]
+	filename := positional[1]
+
+	if !*yes && !confirm(fmt.Sprintf("Delete backup %s/%s from %s storage? [y/N] ", database.Name, filename, cfg.Storage.Target)) {
+		fmt.Println("aborted")
+		os.Exit(1)
+	}
+
+	if err := utils.Delete(provider, database, filename, *force); err != nil {
+		fmt.Println("delete backup error:", err)
+		os.Exit(1)
+	}
+	entry := utils.AuditEntry{Action: "delete", Database: database.Name, Filename: filename, Detail: "storage " + cfg.Storage.Target}
+	if *force {
+		entry.Detail += ", forced"
+	}
+	if err := utils.Audit(cfg.AuditLog, entry); err != nil {
+		fmt.Println("audit log error:", err)
+	}
+	fmt.Printf("deleted %s/%s\n", database.Name, filename)
+}
+
+// confirm asks question on stdout and reports whether the answer is yes.
+func confirm(question string) bool {
+	fmt.Print(question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
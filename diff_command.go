@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"docker-postgres-backuper/archive"
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runDiffCommand implements `diff <database> <backup-file|selector> <backup-file|selector>`.
+// Like diff(1) it exits with status 1 when the schemas differ.
+func runDiffCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	if len(args) != 3 {
+		panic("uncorrected command")
+	}
+
+	changes, err := utils.DiffSchemas(provider, selectDatabases(cfg, args[0])[0], args[1], args[2])
+	if err != nil {
+		fmt.Println("diff backups error:", err)
+		os.Exit(2)
+	}
+	archive.PrintSchemaChanges(os.Stdout, changes)
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,159 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-postgres-backuper/config"
+)
+
+const (
+	labelPrefix = "backuper."
+	enableLabel = labelPrefix + "enable"
+)
+
+// Client queries the Docker Engine API over its unix socket for containers
+// and Swarm services labelled for backup.
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient(socket string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &Client{httpClient: &http.Client{Transport: transport, Timeout: 30 * time.Second}}
+}
+
+type container struct {
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+}
+
+type service struct {
+	Spec struct {
+		Name   string            `json:"Name"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Spec"`
+}
+
+// Discover returns a database for every container or service labelled with
+// backuper.enable=true. Swarm services are only queried when the engine is a
+// swarm manager; otherwise they are silently skipped. Entries with invalid
+// labels are skipped and described by the returned error together with the
+// databases that could be mapped; the database list is nil only when the
+// Docker API itself failed.
+func (c *Client) Discover(ctx context.Context) ([]config.Database, error) {
+	found := map[string]config.Database{}
+	var problems []error
+
+	var services []service
+	status, err := c.get(ctx, "/services", &services)
+	if err != nil && status != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+	for _, item := range services {
+		database, err := fromLabels(item.Spec.Name, item.Spec.Labels)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("service %s: %w", item.Spec.Name, err))
+			continue
+		}
+		found[database.Name] = database
+	}
+
+	var containers []container
+	if _, err := c.get(ctx, "/containers/json", &containers); err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	for _, item := range containers {
+		if len(item.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(item.Names[0], "/")
+		database, err := fromLabels(name, item.Labels)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("container %s: %w", name, err))
+			continue
+		}
+		if _, ok := found[database.Name]; !ok {
+			found[database.Name] = database
+		}
+	}
+
+	databases := make([]config.Database, 0, len(found))
+	for _, database := range found {
+		databases = append(databases, database)
+	}
+	sort.Slice(databases, func(i, j int) bool {
+		return databases[i].Name < databases[j].Name
+	})
+	return databases, errors.Join(problems...)
+}
+
+func (c *Client) get(ctx context.Context, path string, target any) (int, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {enableLabel + "=true"}})
+	if err != nil {
+		return 0, err
+	}
+	endpoint := "http://docker" + path + "?filters=" + url.QueryEscape(string(filters))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("docker request failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return resp.StatusCode, fmt.Errorf("decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// fromLabels maps backuper.* labels onto a database. The container or
+// service name is used as both identifier and host unless overridden.
+func fromLabels(name string, labels map[string]string) (config.Database, error) {
+	if enabled, _ := strconv.ParseBool(labels[enableLabel]); !enabled {
+		return config.Database{}, fmt.Errorf("missing %s=true label", enableLabel)
+	}
+
+	database := config.Database{
+		Name:         labels[labelPrefix+"name"],
+		Host:         labels[labelPrefix+"host"],
+		User:         labels[labelPrefix+"user"],
+		PasswordFile: labels[labelPrefix+"password-file"],
+		Database:     labels[labelPrefix+"database"],
+	}
+	if database.Name == "" {
+		database.Name = name
+	}
+	if database.Host == "" {
+		database.Host = name
+	}
+	if schedule := labels[labelPrefix+"schedule"]; schedule != "" {
+		hours, err := config.ParseHours(schedule)
+		if err != nil {
+			return config.Database{}, fmt.Errorf("%sschedule: %w", labelPrefix, err)
+		}
+		database.Schedule.Hours = hours
+	}
+	return database, nil
+}
@@ -0,0 +1,118 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func startFakeDocker(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen on unix socket: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { _ = server.Close() })
+	return socket
+}
+
+func TestDiscoverContainersAndServices(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filters") == "" {
+			t.Errorf("expected label filter in services request")
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{"Spec": map[string]any{
+				"Name": "billing-database",
+				"Labels": map[string]string{
+					"backuper.enable":        "true",
+					"backuper.name":          "billing",
+					"backuper.user":          "billing",
+					"backuper.password-file": "/run/secrets/billing",
+					"backuper.schedule":      "2,14",
+				},
+			}},
+		})
+	})
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{
+				"Names":  []string{"/users-database"},
+				"Labels": map[string]string{"backuper.enable": "true", "backuper.database": "users"},
+			},
+			{
+				"Names":  []string{"/billing-database.1.abc"},
+				"Labels": map[string]string{"backuper.enable": "true", "backuper.name": "billing"},
+			},
+		})
+	})
+
+	databases, err := NewClient(startFakeDocker(t, mux)).Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(databases) != 2 {
+		t.Fatalf("expected 2 databases, got %+v", databases)
+	}
+
+	billing := databases[0]
+	if billing.Name != "billing" || billing.Host != "billing-database" || billing.User != "billing" {
+		t.Fatalf("unexpected billing database: %+v", billing)
+	}
+	if billing.PasswordFile != "/run/secrets/billing" || len(billing.Schedule.Hours) != 2 {
+		t.Fatalf("unexpected billing settings: %+v", billing)
+	}
+
+	users := databases[1]
+	if users.Name != "users-database" || users.Host != "users-database" || users.Database != "users" {
+		t.Fatalf("unexpected users database: %+v", users)
+	}
+}
+
+func TestDiscoverSkipsServicesWhenNotSwarmManager(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"This node is not a swarm manager."}`, http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{"Names": []string{"/users-database"}, "Labels": map[string]string{"backuper.enable": "true"}},
+		})
+	})
+
+	databases, err := NewClient(startFakeDocker(t, mux)).Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(databases) != 1 || databases[0].Name != "users-database" {
+		t.Fatalf("unexpected databases: %+v", databases)
+	}
+}
+
+func TestDiscoverSkipsInvalidLabels(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{"Names": []string{"/users-database"}, "Labels": map[string]string{"backuper.enable": "true", "backuper.schedule": "daily"}},
+			{"Names": []string{"/content-database"}, "Labels": map[string]string{"backuper.enable": "true"}},
+		})
+	})
+
+	databases, err := NewClient(startFakeDocker(t, mux)).Discover(context.Background())
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(databases) != 1 || databases[0].Name != "content-database" {
+		t.Fatalf("expected valid containers to be kept, got %+v", databases)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/discovery"
+)
+
+// withDiscoveredDatabases extends cfg with databases found through Docker
+// labels when discovery is enabled. Problems with individual entries are
+// logged to stderr; an error is returned only when Docker could not be queried.
+func withDiscoveredDatabases(cfg *config.Config) (*config.Config, error) {
+	if !cfg.Discovery.Enabled {
+		return cfg, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	databases, err := discovery.NewClient(cfg.Discovery.Socket).Discover(ctx)
+	if databases == nil && err != nil {
+		return cfg, fmt.Errorf("docker discovery: %w", err)
+	}
+	if err != nil {
+		log.Println("docker discovery error:", err)
+	}
+
+	merged, problems := cfg.WithDatabases(databases)
+	for _, problem := range problems {
+		log.Println("docker discovery error:", problem)
+	}
+	return merged, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runDoctorCommand runs the preflight checks and prints a human readable
+// report, or JSON when --json is given.
+func runDoctorCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	report := utils.Doctor(provider, cfg.Databases)
+
+	if slices.Contains(args, "--json") {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			panic(err)
+		}
+	} else {
+		report.Print(os.Stdout)
+	}
+
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
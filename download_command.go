@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runDownloadCommand implements `download <database> <backup-file|selector> <destination|->`.
+// With "-" the backup is written to stdout, e.g. for piping into pg_restore.
+func runDownloadCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	positional := parseArgs(fs, args)
+	if len(positional) != 3 {
+		panic("uncorrected command")
+	}
+
+	destination := positional[2]
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if destination != "-" {
+		var err error
+		file, err = os.Create(destination)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "create destination error:", err)
+			os.Exit(1)
+		}
+		w = file
+	}
+
+	filename, err := utils.Download(provider, selectDatabases(cfg, positional[0])[0], positional[1], w)
+	if file != nil {
+		// A failed close may mean the backup was not fully written, and a
+		// partial destination must not be mistaken for a backup.
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("close destination: %w", closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(destination)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "download backup error:", err)
+		os.Exit(1)
+	}
+	if destination != "-" {
+		fmt.Fprintf(os.Stderr, "downloaded %s to %s\n", filename, destination)
+	}
+}
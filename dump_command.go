@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runDumpCommand implements `dump <database|--all> [--schema-only]`.
+func runDumpCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	all := fs.Bool("all", false, "dump every configured database")
+	schemaOnly := fs.Bool("schema-only", false, "create a schema-only backup")
+	positional := parseArgs(fs, args)
+
+	backupType := "manual"
+	if *schemaOnly {
+		backupType = "schema"
+	}
+
+	if *all {
+		if len(positional) != 0 {
+			panic("uncorrected command")
+		}
+		utils.Dump(provider, cfg.Databases, backupType)
+		return
+	}
+
+	if len(positional) != 1 {
+		panic("uncorrected command")
+	}
+	utils.Dump(provider, selectDatabases(cfg, positional[0]), backupType)
+}
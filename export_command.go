@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runExportCommand implements
+// `export <database> <backup-file|selector> --table <table> [--format csv|sql|insert] [--output <path>]`.
+func runExportCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	table := fs.String("table", "", "table to export (schema.table)")
+	format := fs.String("format", "csv", "output format: csv, sql or insert")
+	output := fs.String("output", "", "write to this file instead of stdout")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 || *table == "" {
+		panic("uncorrected command")
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "create output file error:", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	rows, err := utils.Export(provider, selectDatabases(cfg, positional[0])[0], positional[1], *table, *format, w)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export table error:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d rows of %s\n", rows, *table)
+}
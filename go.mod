@@ -1,3 +1,5 @@
 module docker-postgres-backuper
 
 go 1.25
+
+require go.yaml.in/yaml/v3 v3.0.4
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runImportCommand implements `import <database> <path> [--type manual] [--timestamp <time>]`.
+func runImportCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	backupType := fs.String("type", "manual", "backup type: daily, weekly, monthly, manual or schema")
+	timestamp := fs.String("timestamp", "", "backup time, defaults to the creation time in the archive")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		panic("uncorrected command")
+	}
+
+	options := utils.ImportOptions{Type: *backupType}
+	if *timestamp != "" {
+		parsed, err := storage.ParseTimestamp(*timestamp)
+		if err != nil {
+			panic(err)
+		}
+		options.Timestamp = parsed
+	}
+
+	database := selectDatabases(cfg, positional[0])[0]
+	filename, err := utils.Import(provider, database.Name, positional[1], options)
+	if err != nil {
+		fmt.Println("import backup error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("imported %s as %s/%s\n", positional[1], database.Name, filename)
+}
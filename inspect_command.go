@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runInspectCommand implements `inspect <database> <backup-file|selector> [--json]`.
+func runInspectCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the summary as JSON")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		panic("uncorrected command")
+	}
+
+	inspection, err := utils.Inspect(provider, selectDatabases(cfg, positional[0])[0], positional[1])
+	if err != nil {
+		fmt.Println("inspect backup error:", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(inspection); err != nil {
+			panic(err)
+		}
+		return
+	}
+	fmt.Printf("backup:          %s/%s\n", inspection.Database, inspection.Filename)
+	inspection.Print(os.Stdout)
+}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"crypto/hmac"
+	"crypto/md5"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"sort"
@@ -37,11 +40,13 @@ type Client struct {
 
 type ListObject struct {
 	Key          string
+	Size         int64
 	LastModified time.Time
 }
 
 type ListObjectsV2Output struct {
 	Objects               []ListObject
+	CommonPrefixes        []string
 	IsTruncated           bool
 	NextContinuationToken string
 }
@@ -142,12 +147,64 @@ func (c *Client) DeleteObject(ctx context.Context, bucket, key string) error {
 	return nil
 }
 
+// PutObjectTagging replaces the tag set of an object.
+func (c *Client) PutObjectTagging(ctx context.Context, bucket, key string, tags map[string]string) error {
+	type tag struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	}
+	tagging := struct {
+		XMLName xml.Name `xml:"Tagging"`
+		TagSet  []tag    `xml:"TagSet>Tag"`
+	}{}
+	keys := make([]string, 0, len(tags))
+	for name := range tags {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		tagging.TagSet = append(tagging.TagSet, tag{Key: name, Value: tags[name]})
+	}
+	body, err := xml.Marshal(tagging)
+	if err != nil {
+		return fmt.Errorf("encode tagging: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("tagging", "")
+	req, err := c.newRequest(ctx, http.MethodPut, bucket, key, query, hex.EncodeToString(sha256Sum(body)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	digest := md5.Sum(body)
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(digest[:]))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return httpError(resp)
+	}
+	return nil
+}
+
 func (c *Client) ListObjectsV2(ctx context.Context, bucket, prefix, continuationToken string) (ListObjectsV2Output, error) {
+	return c.ListObjectsV2Delimited(ctx, bucket, prefix, "", continuationToken)
+}
+
+// ListObjectsV2Delimited lists objects like ListObjectsV2 but groups keys
+// that contain the delimiter after the prefix into CommonPrefixes.
+func (c *Client) ListObjectsV2Delimited(ctx context.Context, bucket, prefix, delimiter, continuationToken string) (ListObjectsV2Output, error) {
 	query := url.Values{}
 	query.Set("list-type", "2")
 	if prefix != "" {
 		query.Set("prefix", prefix)
 	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
 	if continuationToken != "" {
 		query.Set("continuation-token", continuationToken)
 	}
@@ -173,10 +230,15 @@ func (c *Client) ListObjectsV2(ctx context.Context, bucket, prefix, continuation
 		if err != nil {
 			t = time.Time{}
 		}
-		objects = append(objects, ListObject{Key: item.Key, LastModified: t})
+		objects = append(objects, ListObject{Key: item.Key, Size: item.Size, LastModified: t})
+	}
+	prefixes := make([]string, 0, len(result.CommonPrefixes))
+	for _, item := range result.CommonPrefixes {
+		prefixes = append(prefixes, item.Prefix)
 	}
 	return ListObjectsV2Output{
 		Objects:               objects,
+		CommonPrefixes:        prefixes,
 		IsTruncated:           result.IsTruncated == "true",
 		NextContinuationToken: result.NextContinuationToken,
 	}, nil
@@ -185,12 +247,18 @@ func (c *Client) ListObjectsV2(ctx context.Context, bucket, prefix, continuation
 type listBucketResult struct {
 	XMLName               xml.Name      `xml:"ListBucketResult"`
 	Contents              []objectEntry `xml:"Contents"`
+	CommonPrefixes        []prefixEntry `xml:"CommonPrefixes"`
 	IsTruncated           string        `xml:"IsTruncated"`
 	NextContinuationToken string        `xml:"NextContinuationToken"`
 }
 
+type prefixEntry struct {
+	Prefix string `xml:"Prefix"`
+}
+
 type objectEntry struct {
 	Key          string `xml:"Key"`
+	Size         int64  `xml:"Size"`
 	LastModified string `xml:"LastModified"`
 }
 
@@ -375,5 +443,8 @@ func hostWithBucket(bucket, endpointHost string) string {
 
 func httpError(resp *http.Response) error {
 	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("s3 request failed: status=%d body=%s: %w", resp.StatusCode, strings.TrimSpace(string(bytes.TrimSpace(data))), fs.ErrNotExist)
+	}
 	return fmt.Errorf("s3 request failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(bytes.TrimSpace(data))))
 }
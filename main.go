@@ -1,107 +1,152 @@
 package main
 
 import (
+	"docker-postgres-backuper/config"
 	"docker-postgres-backuper/storage"
 	"docker-postgres-backuper/utils"
 	"fmt"
+	"log"
 	"os"
-	"strconv"
-	"strings"
-	"time"
 )
 
+// minArgs is the least number of arguments each command takes. The commands
+// check their flags and the exact arguments themselves.
+var minArgs = map[string]int{
+	"start":    0,
+	"doctor":   0,
+	"list":     1,
+	"dump":     1,
+	"restore":  2,
+	"inspect":  2,
+	"export":   2,
+	"import":   2,
+	"delete":   2,
+	"pin":      2,
+	"unpin":    2,
+	"download": 3,
+	"diff":     3,
+	"migrate":  4,
+}
+
 func main() {
 	if len(os.Args) == 1 {
 		panic("uncorrected command")
 	}
 
-	s3AccessKeyID, err := getEnvOrFile("S3_ACCESS_KEY_ID")
+	command := os.Args[1]
+	if command == "config" {
+		runConfigCommand(os.Args[2:])
+		return
+	}
+
+	if required, ok := minArgs[command]; !ok || len(os.Args)-2 < required {
+		panic("uncorrected command")
+	}
+
+	cfg, err := config.Load()
 	if err != nil {
 		panic(err)
 	}
-	s3SecretAccessKey, err := getEnvOrFile("S3_SECRET_ACCESS_KEY")
+
+	provider, err := storage.NewProvider(cfg.Storage.Target, cfg.StorageConfig())
 	if err != nil {
 		panic(err)
 	}
 
-	var databaseList []string
-	if os.Getenv("DATABASE_LIST") != "" {
-		databaseList = strings.Split(os.Getenv("DATABASE_LIST"), ",")
-	}
+	if command == "start" {
+		d := newDaemon(cfg, provider)
+		utils.Initialize(provider, d.databases())
+		if cfg.MetricsAddress != "" {
+			serveMetrics(cfg.MetricsAddress, d)
+		}
 
-	command := os.Args[1]
-	if !(command == "start" || (len(os.Args) > 2 && ((command == "restore" && len(os.Args) > 3) || command == "list" || command == "dump"))) {
-		panic("uncorrected command")
+		fmt.Println("Program started...")
+
+		d.run()
+		return
 	}
 
-	backupPath := "backup-data"
-	if os.Getenv("MODE") == "production" {
-		backupPath = utils.BaseBackupDirectoryPath
-	}
-	provider, err := storage.NewProvider(os.Getenv("BACKUP_TARGET"), storage.Config{
-		Local: storage.LocalConfig{BasePath: backupPath},
-		S3: storage.S3Config{
-			Bucket:          os.Getenv("S3_BUCKET"),
-			Prefix:          os.Getenv("S3_PREFIX"),
-			Region:          os.Getenv("S3_REGION"),
-			Endpoint:        os.Getenv("S3_ENDPOINT"),
-			AccessKeyID:     s3AccessKeyID,
-			SecretAccessKey: s3SecretAccessKey,
-			UseTLS:          boolEnv("S3_USE_TLS", true),
-			ForcePathStyle:  boolEnv("S3_FORCE_PATH_STYLE", false),
-		},
-	})
+	// Commands may write backups to stdout, so problems go to stderr.
+	cfg, err = withDiscoveredDatabases(cfg)
 	if err != nil {
-		panic(err)
+		log.Println(err)
 	}
 
 	if command == "list" {
+		if os.Args[2] == "--pending" {
+			utils.ListPending(provider)
+			return
+		}
 		utils.List(provider, os.Args[2])
 		return
 	}
 
 	if command == "restore" {
-		utils.Restore(provider, os.Args[2], os.Args[3], []string{})
+		runRestoreCommand(provider, cfg, os.Args[2:])
 		return
 	}
 
-	if command == "dump" {
-		utils.Dump(provider, os.Args[2], "manual", databaseList)
+	if command == "inspect" {
+		runInspectCommand(provider, cfg, os.Args[2:])
 		return
 	}
 
-	utils.Initialize(provider, databaseList)
+	if command == "export" {
+		runExportCommand(provider, cfg, os.Args[2:])
+		return
+	}
 
-	fmt.Println("Program started...")
+	if command == "diff" {
+		runDiffCommand(provider, cfg, os.Args[2:])
+		return
+	}
 
-	for range time.Tick(time.Hour) {
-		if time.Now().Hour()%utils.IntervalInHours == 3 && os.Getenv("MODE") == "production" {
-			utils.Dump(provider, "--all", utils.GetBackupType(), databaseList)
-		}
+	if command == "import" {
+		runImportCommand(provider, cfg, os.Args[2:])
+		return
 	}
-}
 
-func boolEnv(key string, defaultValue bool) bool {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	if command == "download" {
+		runDownloadCommand(provider, cfg, os.Args[2:])
+		return
 	}
-	parsed, err := strconv.ParseBool(value)
-	if err != nil {
-		return defaultValue
+
+	if command == "delete" {
+		runDeleteCommand(provider, cfg, os.Args[2:])
+		return
 	}
-	return parsed
-}
 
-func getEnvOrFile(key string) (string, error) {
-	filePath := os.Getenv(key + "_FILE")
-	if filePath != "" {
-		value, err := os.ReadFile(filePath)
-		if err != nil {
-			return "", fmt.Errorf("read %s: %w", key+"_FILE", err)
-		}
-		return strings.TrimRight(string(value), "\r\n"), nil
+	if command == "pin" {
+		runPinCommand(provider, cfg, os.Args[2:])
+		return
+	}
+
+	if command == "unpin" {
+		runUnpinCommand(provider, cfg, os.Args[2:])
+		return
 	}
 
-	return os.Getenv(key), nil
+	if command == "migrate" {
+		runMigrateCommand(cfg, os.Args[2:])
+		return
+	}
+
+	if command == "doctor" {
+		runDoctorCommand(provider, cfg, os.Args[2:])
+		return
+	}
+
+	if command == "dump" {
+		runDumpCommand(provider, cfg, os.Args[2:])
+		return
+	}
+}
+
+// selectDatabases resolves a command line database argument.
+func selectDatabases(cfg *config.Config, name string) []config.Database {
+	database, err := cfg.Database(name)
+	if err != nil {
+		panic(err)
+	}
+	return []config.Database{database}
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"docker-postgres-backuper/storage"
+)
+
+// serveMetrics serves the spool state of the daemon's current provider in
+// the Prometheus text format on /metrics.
+func serveMetrics(address string, d *daemon) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeSpoolMetrics(w, storage.Spools(d.state.Load().provider), time.Now())
+	})
+	go func() {
+		if err := http.ListenAndServe(address, mux); err != nil {
+			fmt.Println("metrics server error:", err)
+		}
+	}()
+}
+
+// writeSpoolMetrics writes one sample per spool for each spool metric.
+func writeSpoolMetrics(w io.Writer, spools []*storage.Spool, now time.Time) {
+	type sample struct {
+		target string
+		stats  storage.SpoolStats
+	}
+	var samples []sample
+	for _, spool := range spools {
+		stats, err := spool.Stats()
+		if err != nil {
+			fmt.Println("spool stats error:", err)
+			continue
+		}
+		samples = append(samples, sample{target: spool.Name, stats: stats})
+	}
+
+	metrics := []struct {
+		name, kind, help string
+		value            func(storage.SpoolStats) float64
+	}{
+		{"backuper_spool_pending_files", "gauge", "Backup files waiting in the spool for upload.", func(s storage.SpoolStats) float64 { return float64(s.Files) }},
+		{"backuper_spool_pending_bytes", "gauge", "Size of the backup files waiting in the spool.", func(s storage.SpoolStats) float64 { return float64(s.Bytes) }},
+		{"backuper_spool_oldest_pending_seconds", "gauge", "Age of the oldest file waiting in the spool.", func(s storage.SpoolStats) float64 {
+			if s.Oldest.IsZero() {
+				return 0
+			}
+			return now.Sub(s.Oldest).Seconds()
+		}},
+		{"backuper_spool_upload_failures_total", "counter", "Failed uploads to the target since the start.", func(s storage.SpoolStats) float64 { return float64(s.Failures) }},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, sample := range samples {
+			fmt.Fprintf(w, "%s{target=%q} %g\n", metric.name, sample.target, metric.value(sample.stats))
+		}
+	}
+}
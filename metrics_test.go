@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"docker-postgres-backuper/storage"
+)
+
+func TestWriteSpoolMetrics(t *testing.T) {
+	// A file in place of the remote base path makes every upload fail.
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	writeFile(t, remotePath, "")
+	spool := storage.NewSpool("metrics", storage.NewLocalProvider(remotePath), t.TempDir(), 1<<20)
+
+	backup := filepath.Join(t.TempDir(), "file_daily.dump")
+	writeFile(t, backup, "backup")
+	if err := spool.Save("users", "file_daily.dump", backup); err != nil {
+		t.Fatalf("expected failed upload to be spooled, got %v", err)
+	}
+	pending, err := spool.Pending()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("expected one pending file, got %+v, %v", pending, err)
+	}
+
+	var out strings.Builder
+	writeSpoolMetrics(&out, []*storage.Spool{spool}, pending[0].Spooled.Add(90*time.Second))
+
+	for _, line := range []string{
+		"# TYPE backuper_spool_pending_files gauge",
+		`backuper_spool_pending_files{target="metrics"} 1`,
+		`backuper_spool_pending_bytes{target="metrics"} 6`,
+		`backuper_spool_oldest_pending_seconds{target="metrics"} 90`,
+		"# TYPE backuper_spool_upload_failures_total counter",
+		`backuper_spool_upload_failures_total{target="metrics"} 1`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("expected %q in metrics output:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// runMigrateCommand implements `migrate --from <target> --to <target> [--database a,b] [--delete-source]`.
+// Targets are named storage targets or "local", "local:<path>", "s3" or
+// "s3://<bucket>[/<prefix>]".
+func runMigrateCommand(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fromTarget := fs.String("from", "", "storage target to copy backups from")
+	toTarget := fs.String("to", "", "storage target to copy backups to")
+	databases := fs.String("database", "", "comma-separated databases to migrate (default: all configured)")
+	deleteSource := fs.Bool("delete-source", false, "delete each backup from the source once its copy is verified")
+	positional := parseArgs(fs, args)
+	if len(positional) != 0 || *fromTarget == "" || *toTarget == "" {
+		panic("uncorrected command")
+	}
+
+	storageCfg := cfg.StorageConfig()
+	fromSpec := storage.TargetSpec(*fromTarget, storageCfg)
+	toSpec := storage.TargetSpec(*toTarget, storageCfg)
+	if fromSpec == toSpec {
+		panic("uncorrected command")
+	}
+
+	from, err := storage.NewTargetProvider(fromSpec, storageCfg)
+	if err != nil {
+		panic(err)
+	}
+	to, err := storage.NewTargetProvider(toSpec, storageCfg)
+	if err != nil {
+		panic(err)
+	}
+
+	names := splitList(*databases)
+	if len(names) == 0 {
+		for _, database := range cfg.Databases {
+			names = append(names, database.Name)
+		}
+	}
+
+	failed := false
+	for _, name := range names {
+		stats, err := storage.Migrate(from, to, name, *deleteSource)
+		status := "ok  "
+		if err != nil {
+			failed = true
+			status = "fail"
+		}
+		fmt.Printf("[%s] %s: copied %d, skipped %d, deleted %d\n", status, name, stats.Copied, stats.Skipped, stats.Deleted)
+		if err != nil {
+			fmt.Println("       migrate error:", err)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
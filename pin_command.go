@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runPinCommand implements `pin <database> <backup-file> --reason <text> --owner <name>`.
+// Pinned backups are never removed by the retention policy.
+func runPinCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	reason := fs.String("reason", "", "why the backup is kept, e.g. a legal hold reference")
+	owner := fs.String("owner", "", "who asked for the backup to be kept")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 || *reason == "" || *owner == "" {
+		panic("uncorrected command")
+	}
+	database := selectDatabases(cfg, positional[0])[0]
+	filename := positional[1]
+
+	if err := utils.Pin(provider, database, filename, *reason, *owner); err != nil {
+		fmt.Println("pin backup error:", err)
+		os.Exit(1)
+	}
+	entry := utils.AuditEntry{Action: "pin", Database: database.Name, Filename: filename, Detail: fmt.Sprintf("owner %s: %s", *owner, *reason)}
+	if err := utils.Audit(cfg.AuditLog, entry); err != nil {
+		fmt.Println("audit log error:", err)
+	}
+	fmt.Printf("pinned %s/%s\n", database.Name, filename)
+}
+
+// runUnpinCommand implements `unpin <database> <backup-file>`.
+func runUnpinCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("unpin", flag.ExitOnError)
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		panic("uncorrected command")
+	}
+	database := selectDatabases(cfg, positional[0])[0]
+	filename := positional[1]
+
+	if err := utils.Unpin(provider, database, filename); err != nil {
+		fmt.Println("unpin backup error:", err)
+		os.Exit(1)
+	}
+	entry := utils.AuditEntry{Action: "unpin", Database: database.Name, Filename: filename}
+	if err := utils.Audit(cfg.AuditLog, entry); err != nil {
+		fmt.Println("audit log error:", err)
+	}
+	fmt.Printf("unpinned %s/%s\n", database.Name, filename)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+	"docker-postgres-backuper/utils"
+)
+
+// runRestoreCommand implements `restore <database|--all> <backup-file|selector> [options]`,
+// `restore <database|--all> --run <id|latest> [options]` and
+// `restore --source <database> --target <database|url> <backup-file|selector> [options]`.
+func runRestoreCommand(provider storage.Provider, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	all := fs.Bool("all", false, "restore every configured database")
+	withGlobals := fs.Bool("with-globals", false, "apply roles and tablespaces from the same run first")
+	at := fs.String("at", "", "restore the newest backup at or before this time")
+	run := fs.String("run", "", "restore the dumps of one backup run, by ID or latest")
+	source := fs.String("source", "", "database whose backup is cloned")
+	target := fs.String("target", "", "database, <database>/<dbname> or postgres:// URL to clone into")
+	createDatabase := fs.Bool("create-database", false, "create the clone target database when missing")
+	noSafetySnapshot := fs.Bool("no-safety-snapshot", false, "skip the pre-restore dump of the target")
+	terminateSessions := fs.Bool("terminate-sessions", false, "block new connections and terminate sessions to the target")
+	singleTransaction := fs.Bool("single-transaction", false, "run pg_restore in a single transaction")
+	exitOnError := fs.Bool("exit-on-error", false, "stop pg_restore at the first error")
+	analyze := fs.Bool("analyze", false, "run ANALYZE after the restore")
+	vacuumAnalyze := fs.Bool("vacuum-analyze", false, "run VACUUM ANALYZE after the restore")
+	tables := fs.String("table", "", "comma-separated tables (schema.table) to restore")
+	schemas := fs.String("schema", "", "comma-separated schemas to restore")
+	dataOnly := fs.Bool("data-only", false, "restore only table data into the existing tables")
+	intoSchema := fs.String("into-schema", "", "restore the selected tables into this side schema")
+	scratchDatabase := fs.String("scratch-database", "", "restore the selected tables into this database on the same server")
+	positional := parseArgs(fs, args)
+
+	options := utils.RestoreOptions{
+		WithGlobals:        *withGlobals,
+		CreateDatabase:     *createDatabase,
+		SkipSafetySnapshot: *noSafetySnapshot,
+		TerminateSessions:  *terminateSessions,
+		SingleTransaction:  *singleTransaction,
+		ExitOnError:        *exitOnError,
+		Analyze:            *analyze,
+		VacuumAnalyze:      *vacuumAnalyze,
+		Tables:             splitList(*tables),
+		Schemas:            splitList(*schemas),
+		DataOnly:           *dataOnly,
+		IntoSchema:         *intoSchema,
+		ScratchDatabase:    *scratchDatabase,
+	}
+	if *at != "" {
+		// --at replaces the backup file argument.
+		positional = append(positional, "at:"+*at)
+	}
+
+	isolated := *intoSchema != "" || *scratchDatabase != ""
+	if isolated && (len(options.Tables)+len(options.Schemas) == 0 || *intoSchema != "" && *scratchDatabase != "" ||
+		*dataOnly || *all || *run != "" || *source != "" || *target != "") {
+		panic("uncorrected command")
+	}
+
+	if *source != "" || *target != "" {
+		if *source == "" || *target == "" || *all || *run != "" || len(positional) != 1 {
+			panic("uncorrected command")
+		}
+		targetDatabase, err := cfg.Target(*target)
+		if err != nil {
+			panic(err)
+		}
+		result := utils.Clone(provider, selectDatabases(cfg, *source)[0], positional[0], targetDatabase, options)
+		if !printRestoreResults([]utils.RestoreResult{result}) {
+			os.Exit(1)
+		}
+		return
+	}
+	if *createDatabase {
+		panic("uncorrected command")
+	}
+
+	var databases []config.Database
+	if *all {
+		databases = cfg.Databases
+	} else {
+		if len(positional) == 0 {
+			panic("uncorrected command")
+		}
+		databases = selectDatabases(cfg, positional[0])
+		positional = positional[1:]
+	}
+
+	var results []utils.RestoreResult
+	switch {
+	case *run != "" && len(positional) == 0:
+		results = utils.RestoreRun(provider, databases, *run, options)
+	case *run == "" && len(positional) == 1:
+		results = utils.Restore(provider, databases, positional[0], options)
+	default:
+		panic("uncorrected command")
+	}
+
+	if !printRestoreResults(results) {
+		os.Exit(1)
+	}
+}
+
+// printRestoreResults prints one line per database and reports whether all
+// restores succeeded.
+func printRestoreResults(results []utils.RestoreResult) bool {
+	ok := true
+	for _, result := range results {
+		name := result.Database
+		if name == "" {
+			name = "restore"
+		}
+		if result.Err != nil {
+			ok = false
+			fmt.Printf("[fail] %s: %v\n", name, result.Err)
+		} else {
+			fmt.Printf("[ok  ] %s: restored %s\n", name, result.Filename)
+		}
+		if result.Rollback != "" {
+			fmt.Printf("       roll back with: %s\n", result.Rollback)
+		}
+	}
+	return ok
+}
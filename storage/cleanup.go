@@ -1,23 +1,76 @@
 package storage
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"strings"
 	"time"
 )
 
-// Cleanup applies the retention policy shared across providers.
-func Cleanup(p Provider, database string, now time.Time) error {
+// RetentionPolicy describes how long each backup type is kept.
+type RetentionPolicy struct {
+	Daily      time.Duration
+	Weekly     time.Duration
+	Monthly    time.Duration
+	Manual     time.Duration
+	Schema     time.Duration
+	PreRestore time.Duration
+}
+
+// Override returns the policy with every non-zero duration of override
+// replacing the corresponding one.
+func (p RetentionPolicy) Override(override RetentionPolicy) RetentionPolicy {
+	for _, field := range []struct{ value, override *time.Duration }{
+		{&p.Daily, &override.Daily},
+		{&p.Weekly, &override.Weekly},
+		{&p.Monthly, &override.Monthly},
+		{&p.Manual, &override.Manual},
+		{&p.Schema, &override.Schema},
+		{&p.PreRestore, &override.PreRestore},
+	} {
+		if *field.override != 0 {
+			*field.value = *field.override
+		}
+	}
+	return p
+}
+
+// Cleanup applies the retention policy shared across providers. The age of a
+// backup is taken from the timestamp in its name, so imported and copied
+// backups age like the original; the modification time is only used for
+// names without a timestamp. Pinned backups are never deleted.
+//
+// Replicated backups are cleaned up on every target separately, with the
+// retention of the target overriding policy.
+func Cleanup(p Provider, database string, now time.Time, policy RetentionPolicy) error {
+	if multi, ok := p.(*multiProvider); ok {
+		var problems []error
+		for _, target := range multi.targets {
+			if err := Cleanup(target.Provider, database, now, policy.Override(target.Retention)); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+			}
+		}
+		return errors.Join(problems...)
+	}
+
 	files, err := p.List(database)
 	if err != nil {
 		return err
 	}
+	var problems []error
 
-	dailyRetention := now.Add(-7 * 24 * time.Hour)
-	weeklyRetention := now.Add(-30 * 24 * time.Hour)
-	monthlyRetention := now.Add(-365 * 24 * time.Hour)
-	manualRetention := now.Add(-365 * 24 * time.Hour)
+	dailyRetention := now.Add(-policy.Daily)
+	weeklyRetention := now.Add(-policy.Weekly)
+	monthlyRetention := now.Add(-policy.Monthly)
+	manualRetention := now.Add(-policy.Manual)
+	schemaRetention := now.Add(-policy.Schema)
+	preRestoreRetention := now.Add(-policy.PreRestore)
 
 	for _, file := range files {
+		if IsManifest(file.Name) {
+			continue
+		}
 		parts := strings.Split(file.Name, "_")
 		if len(parts) < 2 {
 			continue
@@ -33,13 +86,32 @@ func Cleanup(p Provider, database string, now time.Time) error {
 			cutoff = monthlyRetention
 		case "manual":
 			cutoff = manualRetention
+		case "schema":
+			cutoff = schemaRetention
+		case PreRestoreType:
+			cutoff = preRestoreRetention
 		default:
 			continue
 		}
-		if !file.Modified.IsZero() && file.Modified.Before(cutoff) {
-			_ = p.Delete(database, file.Name)
+		created := file.Modified
+		if named, ok := artifactCreated(file.Name); ok {
+			created = named
+		}
+		if !created.IsZero() && created.Before(cutoff) {
+			manifest, err := LoadManifest(p, database, file.Name)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				// Without the manifest a pin cannot be ruled out.
+				problems = append(problems, fmt.Errorf("keep %s: load manifest: %w", file.Name, err))
+				continue
+			}
+			if err == nil && manifest.Pin != nil {
+				continue
+			}
+			if err := p.Delete(database, file.Name); err == nil {
+				_ = p.Delete(database, ManifestName(file.Name))
+			}
 		}
 	}
 
-	return nil
+	return errors.Join(problems...)
 }
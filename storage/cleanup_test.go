@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanupRemovesExpiredBackupsWithManifests(t *testing.T) {
+	basePath := t.TempDir()
+	provider := NewLocalProvider(basePath)
+	if err := provider.EnsureDatabase("testdb"); err != nil {
+		t.Fatalf("ensure database: %v", err)
+	}
+
+	now := time.Now()
+	files := map[string]time.Time{
+		"file_daily_old.dump":               now.Add(-10 * 24 * time.Hour),
+		"file_daily_old.dump.manifest.json": now.Add(-10 * 24 * time.Hour),
+		"file_daily_new.dump":               now.Add(-1 * 24 * time.Hour),
+		"file_daily_new.dump.manifest.json": now.Add(-1 * 24 * time.Hour),
+		"file_schema_old.dump":              now.Add(-40 * 24 * time.Hour),
+		"file_manual_recent.dump":           now.Add(-40 * 24 * time.Hour),
+		// The timestamp in the name wins over the modification time.
+		"file_daily_" + now.Add(-9*24*time.Hour).Format(time.RFC3339) + ".dump":   now,
+		"file_daily_" + now.Add(-2*24*time.Hour).Format(time.RFC3339) + ".dump":   now.Add(-20 * 24 * time.Hour),
+		"globals_daily_" + now.Add(-9*24*time.Hour).Format(time.RFC3339) + ".sql": now,
+	}
+	for name, modified := range files {
+		path := filepath.Join(basePath, "testdb", name)
+		content := name
+		if IsManifest(name) {
+			content = "{}"
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatalf("set times for %s: %v", name, err)
+		}
+	}
+
+	policy := RetentionPolicy{Daily: 7 * 24 * time.Hour, Manual: 365 * 24 * time.Hour, Schema: 30 * 24 * time.Hour}
+	if err := Cleanup(provider, "testdb", now, policy); err != nil {
+		t.Fatalf("Cleanup returned error: %v", err)
+	}
+
+	remaining, err := provider.List("testdb")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	names := map[string]bool{}
+	for _, file := range remaining {
+		names[file.Name] = true
+	}
+	expected := []string{
+		"file_daily_new.dump",
+		"file_daily_new.dump.manifest.json",
+		"file_manual_recent.dump",
+		"file_daily_" + now.Add(-2*24*time.Hour).Format(time.RFC3339) + ".dump",
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("unexpected remaining files: %v", names)
+	}
+	for _, name := range expected {
+		if !names[name] {
+			t.Fatalf("expected %s to be kept, remaining: %v", name, names)
+		}
+	}
+}
+
+func TestCleanupKeepsPinnedBackups(t *testing.T) {
+	basePath := t.TempDir()
+	provider := NewLocalProvider(basePath)
+	if err := provider.EnsureDatabase("testdb"); err != nil {
+		t.Fatalf("ensure database: %v", err)
+	}
+
+	now := time.Now()
+	pinned := "file_daily_" + now.Add(-30*24*time.Hour).Format(time.RFC3339) + ".dump"
+	expired := "file_daily_" + now.Add(-20*24*time.Hour).Format(time.RFC3339) + ".dump"
+	unreadable := "file_daily_" + now.Add(-10*24*time.Hour).Format(time.RFC3339) + ".dump"
+	files := map[string]string{pinned: pinned, expired: expired, unreadable: unreadable, ManifestName(unreadable): "{"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(basePath, "testdb", name), []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	manifest := Manifest{Database: "testdb", Filename: pinned, Pin: &Pin{Reason: "legal hold", Owner: "legal", Pinned: now}}
+	if err := SaveManifest(provider, "testdb", manifest); err != nil {
+		t.Fatalf("save manifest: %v", err)
+	}
+
+	// A manifest that cannot be read may hold a pin, so the backup is kept
+	// and the problem reported.
+	if err := Cleanup(provider, "testdb", now, RetentionPolicy{Daily: 7 * 24 * time.Hour}); err == nil {
+		t.Fatal("expected the unreadable manifest to be reported")
+	}
+
+	remaining, err := provider.List("testdb")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	names := map[string]bool{}
+	for _, file := range remaining {
+		names[file.Name] = true
+	}
+	if len(names) != 4 || !names[pinned] || !names[ManifestName(pinned)] || !names[unreadable] {
+		t.Fatalf("unexpected remaining files: %v", names)
+	}
+}
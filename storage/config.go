@@ -2,12 +2,27 @@ package storage
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 )
 
 // Config aggregates provider specific configuration.
 type Config struct {
 	Local LocalConfig
 	S3    S3Config
+	// Targets holds named targets that may be listed in the backup target
+	// next to "local" and "s3".
+	Targets map[string]TargetConfig
+	// Spool keeps failed uploads to S3 targets for later retry.
+	Spool SpoolConfig
+}
+
+// TargetConfig is a named storage target. Spec uses the syntax accepted by
+// NewTargetProvider and defaults to the name; non-zero Retention durations
+// override the retention policy of the database on this target.
+type TargetConfig struct {
+	Spec      string
+	Retention RetentionPolicy
 }
 
 type LocalConfig struct {
@@ -25,8 +40,54 @@ type S3Config struct {
 	ForcePathStyle  bool
 }
 
-// NewProvider builds the concrete storage provider based on the requested target.
+// NewProvider builds the storage provider for the requested target. A
+// comma-separated list of targets, such as "local,s3", or a named target
+// yields a provider that replicates every backup to all of them. S3 targets
+// are wrapped with a spool when one is configured.
 func NewProvider(target string, cfg Config) (Provider, error) {
+	names := strings.Split(target, ",")
+	if _, named := cfg.Targets[target]; len(names) == 1 && !named {
+		provider, err := newProvider(target, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return withSpool(target, target, provider, cfg), nil
+	}
+
+	var targets []Target
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		targetCfg := cfg.Targets[name]
+		spec := TargetSpec(name, cfg)
+		provider, err := NewTargetProvider(spec, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", name, err)
+		}
+		provider = withSpool(name, spec, provider, cfg)
+		targets = append(targets, Target{Name: name, Provider: provider, Retention: targetCfg.Retention})
+	}
+	return NewMultiProvider(targets), nil
+}
+
+// TargetSpec resolves a named target to its specification. Names that are not
+// configured are returned unchanged, as they are taken to be specifications.
+func TargetSpec(name string, cfg Config) string {
+	if target, ok := cfg.Targets[name]; ok && target.Spec != "" {
+		return target.Spec
+	}
+	return name
+}
+
+// withSpool wraps providers of remote targets with a spool in a directory
+// named after the target.
+func withSpool(name, spec string, provider Provider, cfg Config) Provider {
+	if cfg.Spool.MaxBytes <= 0 || spec != "s3" && !strings.HasPrefix(spec, "s3://") {
+		return provider
+	}
+	return NewSpool(name, provider, filepath.Join(cfg.Spool.Path, name), cfg.Spool.MaxBytes)
+}
+
+func newProvider(target string, cfg Config) (Provider, error) {
 	switch target {
 	case "", "local":
 		if cfg.Local.BasePath == "" {
@@ -42,3 +103,24 @@ func NewProvider(target string, cfg Config) (Provider, error) {
 		return nil, fmt.Errorf("unsupported backup target: %s", target)
 	}
 }
+
+// NewTargetProvider builds a provider from a target specification such as
+// "local", "local:/backups", "s3" or "s3://bucket/prefix". The settings in cfg
+// are used for everything the specification does not override, so another
+// bucket is reached with the configured endpoint and credentials.
+func NewTargetProvider(spec string, cfg Config) (Provider, error) {
+	switch {
+	case spec == "local" || spec == "s3":
+		return newProvider(spec, cfg)
+	case strings.HasPrefix(spec, "local:"):
+		cfg.Local.BasePath = strings.TrimPrefix(spec, "local:")
+		return newProvider("local", cfg)
+	case strings.HasPrefix(spec, "s3://"):
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(spec, "s3://"), "/")
+		cfg.S3.Bucket = bucket
+		cfg.S3.Prefix = prefix
+		return newProvider("s3", cfg)
+	default:
+		return nil, fmt.Errorf("unsupported storage target: %s", spec)
+	}
+}
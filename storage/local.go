@@ -44,15 +44,32 @@ func (p *localProvider) List(database string) ([]FileInfo, error) {
 	}
 	infos := make([]FileInfo, 0, len(entries))
 	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
 		fi, err := entry.Info()
 		if err != nil {
 			return nil, err
 		}
-		infos = append(infos, FileInfo{Name: entry.Name(), Modified: fi.ModTime()})
+		infos = append(infos, FileInfo{Name: entry.Name(), Size: fi.Size(), Modified: fi.ModTime()})
 	}
 	return infos, nil
 }
 
+func (p *localProvider) Children(database string) ([]string, error) {
+	entries, err := os.ReadDir(p.databasePath(database))
+	if err != nil {
+		return nil, err
+	}
+	var children []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			children = append(children, entry.Name())
+		}
+	}
+	return children, nil
+}
+
 func (p *localProvider) Fetch(database, filename string) (string, func() error, error) {
 	path := filepath.Join(p.databasePath(database), filename)
 	if _, err := os.Stat(path); err != nil {
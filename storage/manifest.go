@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	manifestSuffix = ".manifest.json"
+	runIDLayout    = "20060102T150405Z"
+)
+
+// Manifest describes a backup artifact. It is stored next to the artifact
+// as "<filename>.manifest.json".
+type Manifest struct {
+	Database   string    `json:"database"`
+	Filename   string    `json:"filename"`
+	Type       string    `json:"type"`
+	RunID      string    `json:"run_id,omitempty"`
+	Created    time.Time `json:"created"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	SchemaOnly bool      `json:"schema_only,omitempty"`
+	Filters    []string  `json:"filters,omitempty"`
+	DumpArgs   []string  `json:"dump_args,omitempty"`
+	// ImportedFrom is the original file name of a dump added with import.
+	ImportedFrom string `json:"imported_from,omitempty"`
+	// Pin exempts the backup from retention while set.
+	Pin *Pin `json:"pin,omitempty"`
+}
+
+// PinnedTag marks pinned objects on providers that support tags.
+const PinnedTag = "backuper-pinned"
+
+// Pin records why and by whom a backup is kept indefinitely.
+type Pin struct {
+	Reason string    `json:"reason"`
+	Owner  string    `json:"owner"`
+	Pinned time.Time `json:"pinned"`
+}
+
+// NewRunID returns the identifier shared by all dumps of a run started at t.
+// Run IDs sort in chronological order.
+func NewRunID(t time.Time) string {
+	return t.UTC().Format(runIDLayout)
+}
+
+// ManifestName returns the name of the manifest that belongs to filename.
+func ManifestName(filename string) string {
+	return filename + manifestSuffix
+}
+
+// IsManifest reports whether name is a manifest rather than an artifact.
+func IsManifest(name string) bool {
+	return strings.HasSuffix(name, manifestSuffix)
+}
+
+// Checksum returns the size and hex encoded SHA-256 digest of a local file.
+func Checksum(localPath string) (int64, string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return 0, "", fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("hash file: %w", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SaveManifest stores the manifest next to its artifact.
+func SaveManifest(p Provider, database string, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	tempFile, err := os.CreateTemp("", "manifest-*.json")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	return p.Save(database, ManifestName(manifest.Filename), tempPath)
+}
+
+// LoadManifest reads the manifest of filename.
+func LoadManifest(p Provider, database, filename string) (Manifest, error) {
+	localPath, cleanup, err := p.Fetch(database, ManifestName(filename))
+	if err != nil {
+		return Manifest{}, err
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("decode manifest: %w", err)
+	}
+	return manifest, nil
+}
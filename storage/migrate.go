@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// MigrateStats counts the artifacts handled by Migrate.
+type MigrateStats struct {
+	Copied  int
+	Skipped int
+	Deleted int
+}
+
+// Migrate copies every artifact of database, including nested keys, from one
+// provider to another together with its manifest. Each copy is verified
+// against the checksum of the source; artifacts already present at the
+// destination with the same checksum are skipped and different ones are
+// reported instead of being overwritten. With deleteSource the source
+// artifact is removed once its copy has been verified. File names are kept,
+// so retention, which uses the timestamp in the names of backups and their
+// globals, is not affected. A
+// database without backups at the source has nothing to migrate.
+func Migrate(from, to Provider, database string, deleteSource bool) (MigrateStats, error) {
+	var stats MigrateStats
+	files, err := from.List(database)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("list %s: %w", database, err)
+	}
+	existing, err := listExisting(to, database)
+	if err != nil {
+		return stats, fmt.Errorf("list %s at destination: %w", database, err)
+	}
+	if err := to.EnsureDatabase(database); err != nil {
+		return stats, fmt.Errorf("prepare %s at destination: %w", database, err)
+	}
+
+	names := map[string]bool{}
+	for _, file := range files {
+		names[file.Name] = true
+	}
+	var problems []error
+	for _, file := range files {
+		if IsManifest(file.Name) {
+			continue
+		}
+		copied, err := migrateArtifact(from, to, database, file.Name, names[ManifestName(file.Name)], existing)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s/%s: %w", database, file.Name, err))
+			continue
+		}
+		if copied {
+			stats.Copied++
+			log.Printf("migrated %s/%s", database, file.Name)
+		} else {
+			stats.Skipped++
+		}
+		if deleteSource {
+			if err := from.Delete(database, file.Name); err != nil {
+				problems = append(problems, fmt.Errorf("delete %s/%s: %w", database, file.Name, err))
+				continue
+			}
+			if names[ManifestName(file.Name)] {
+				_ = from.Delete(database, ManifestName(file.Name))
+			}
+			stats.Deleted++
+		}
+	}
+
+	children, err := from.Children(database)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("list children of %s: %w", database, err))
+	}
+	for _, child := range children {
+		nested, err := Migrate(from, to, database+"/"+child, deleteSource)
+		stats.Copied += nested.Copied
+		stats.Skipped += nested.Skipped
+		stats.Deleted += nested.Deleted
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return stats, errors.Join(problems...)
+}
+
+// migrateArtifact copies one artifact and its manifest and reports whether
+// the artifact had to be copied.
+func migrateArtifact(from, to Provider, database, filename string, hasManifest bool, existing map[string]bool) (bool, error) {
+	tempPath, err := fetchCopy(from, database, filename)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tempPath)
+	_, sum, err := Checksum(tempPath)
+	if err != nil {
+		return false, err
+	}
+	var manifest Manifest
+	if hasManifest {
+		manifest, err = LoadManifest(from, database, filename)
+		if err != nil {
+			return false, fmt.Errorf("load manifest: %w", err)
+		}
+		if manifest.SHA256 != "" && manifest.SHA256 != sum {
+			return false, fmt.Errorf("checksum mismatch at source: manifest has %s, file has %s", manifest.SHA256, sum)
+		}
+	}
+
+	copied := false
+	if existing[filename] {
+		if err := verifyCopy(to, database, filename, sum); err != nil {
+			return false, fmt.Errorf("already present at destination: %w", err)
+		}
+	} else {
+		if err := to.Save(database, filename, tempPath); err != nil {
+			return false, fmt.Errorf("save: %w", err)
+		}
+		if err := verifyCopy(to, database, filename, sum); err != nil {
+			return false, err
+		}
+		copied = true
+	}
+
+	if hasManifest && !existing[ManifestName(filename)] {
+		manifestPath, err := fetchCopy(from, database, ManifestName(filename))
+		if err != nil {
+			return copied, err
+		}
+		defer os.Remove(manifestPath)
+		if err := to.Save(database, ManifestName(filename), manifestPath); err != nil {
+			return copied, fmt.Errorf("save manifest: %w", err)
+		}
+	}
+	// Object tags are not part of the copy, so pins are tagged again.
+	if tagger, ok := to.(Tagger); ok && manifest.Pin != nil {
+		if err := tagger.SetTags(database, filename, map[string]string{PinnedTag: "true"}); err != nil {
+			return copied, fmt.Errorf("tag pinned copy: %w", err)
+		}
+	}
+	return copied, nil
+}
+
+// fetchCopy fetches filename into a temporary file owned by the caller, as
+// saving to a local provider moves the file away.
+func fetchCopy(p Provider, database, filename string) (string, error) {
+	localPath, cleanup, err := p.Fetch(database, filename)
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", filename, err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	tempFile, err := os.CreateTemp("", "migrate-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	tempFile.Close()
+	if err := copyFile(localPath, tempPath); err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return tempPath, nil
+}
+
+// verifyCopy compares the checksum of filename at the provider with sum.
+func verifyCopy(p Provider, database, filename, sum string) error {
+	localPath, cleanup, err := p.Fetch(database, filename)
+	if err != nil {
+		return fmt.Errorf("fetch copy: %w", err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	_, copySum, err := Checksum(localPath)
+	if err != nil {
+		return err
+	}
+	if copySum != sum {
+		return fmt.Errorf("checksum mismatch: source has %s, destination has %s", sum, copySum)
+	}
+	return nil
+}
+
+// listExisting returns the names stored under database. A key that does not
+// exist yet has no files.
+func listExisting(p Provider, database string) (map[string]bool, error) {
+	files, err := p.List(database)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, file := range files {
+		existing[file.Name] = true
+	}
+	return existing, nil
+}
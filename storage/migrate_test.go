@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateCopiesVerifiesAndSkips(t *testing.T) {
+	fromPath := t.TempDir()
+	toPath := t.TempDir()
+	from := NewLocalProvider(fromPath)
+	to := NewLocalProvider(toPath)
+
+	writeFiles := func(basePath string, files map[string]string) {
+		for name, content := range files {
+			path := filepath.Join(basePath, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatalf("create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("write %s: %v", name, err)
+			}
+		}
+	}
+	writeFiles(fromPath, map[string]string{
+		"users/file_daily_2025-07-04T09:00:00Z.dump":     "daily",
+		"users/file_manual_2025-07-05T09:00:00Z.dump":    "manual",
+		"users/globals_daily_2025-07-04T09:00:00Z.sql":   "globals",
+		"users/app/file_daily_2025-07-04T09:00:00Z.dump": "nested",
+	})
+	writeFiles(toPath, map[string]string{
+		"users/file_manual_2025-07-05T09:00:00Z.dump": "manual",
+	})
+	size, sum, err := Checksum(filepath.Join(fromPath, "users", "file_daily_2025-07-04T09:00:00Z.dump"))
+	if err != nil {
+		t.Fatalf("checksum: %v", err)
+	}
+	manifest := Manifest{Database: "users", Filename: "file_daily_2025-07-04T09:00:00Z.dump", Type: "daily", Size: size, SHA256: sum}
+	if err := SaveManifest(from, "users", manifest); err != nil {
+		t.Fatalf("save manifest: %v", err)
+	}
+
+	stats, err := Migrate(from, to, "users", false)
+	if err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if stats.Copied != 3 || stats.Skipped != 1 || stats.Deleted != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	for _, name := range []string{
+		"users/file_daily_2025-07-04T09:00:00Z.dump",
+		"users/file_daily_2025-07-04T09:00:00Z.dump.manifest.json",
+		"users/globals_daily_2025-07-04T09:00:00Z.sql",
+		"users/app/file_daily_2025-07-04T09:00:00Z.dump",
+	} {
+		if _, err := os.Stat(filepath.Join(toPath, name)); err != nil {
+			t.Fatalf("expected %s to be copied: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(fromPath, name)); err != nil {
+			t.Fatalf("expected %s to be kept at the source: %v", name, err)
+		}
+	}
+
+	// A file that differs at the destination is never overwritten.
+	writeFiles(toPath, map[string]string{"users/file_manual_2025-07-05T09:00:00Z.dump": "changed"})
+	if _, err := Migrate(from, to, "users", true); err == nil {
+		t.Fatal("expected a differing destination file to be reported")
+	}
+	if _, err := os.Stat(filepath.Join(fromPath, "users", "file_manual_2025-07-05T09:00:00Z.dump")); err != nil {
+		t.Fatalf("expected unverified source to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(fromPath, "users", "file_daily_2025-07-04T09:00:00Z.dump")); !os.IsNotExist(err) {
+		t.Fatalf("expected verified source to be deleted, got %v", err)
+	}
+}
+
+func TestMigrateSkipsDatabasesWithoutBackups(t *testing.T) {
+	from := NewLocalProvider(t.TempDir())
+	to := NewLocalProvider(t.TempDir())
+
+	stats, err := Migrate(from, to, "users", false)
+	if err != nil || stats != (MigrateStats{}) {
+		t.Fatalf("expected nothing to migrate, got %+v, %v", stats, err)
+	}
+}
+
+type taggingProvider struct {
+	Provider
+	tags map[string]map[string]string
+}
+
+func (p *taggingProvider) SetTags(database, filename string, tags map[string]string) error {
+	p.tags[database+"/"+filename] = tags
+	return nil
+}
+
+func TestMigrateTagsPinnedCopies(t *testing.T) {
+	fromPath := t.TempDir()
+	from := NewLocalProvider(fromPath)
+	to := &taggingProvider{Provider: NewLocalProvider(t.TempDir()), tags: map[string]map[string]string{}}
+
+	for _, name := range []string{"file_manual_2025-07-04T09:00:00Z.dump", "file_daily_2025-07-04T09:00:00Z.dump"} {
+		path := filepath.Join(fromPath, "users", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	pinned := Manifest{Filename: "file_manual_2025-07-04T09:00:00Z.dump", Pin: &Pin{Reason: "audit"}}
+	if err := SaveManifest(from, "users", pinned); err != nil {
+		t.Fatalf("save manifest: %v", err)
+	}
+
+	if _, err := Migrate(from, to, "users", false); err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if len(to.tags) != 1 || to.tags["users/file_manual_2025-07-04T09:00:00Z.dump"][PinnedTag] != "true" {
+		t.Fatalf("expected only the pinned copy to be tagged, got %v", to.tags)
+	}
+}
+
+func TestNewTargetProviderRejectsUnknownTargets(t *testing.T) {
+	if _, err := NewTargetProvider("ftp://backups", Config{}); err == nil {
+		t.Fatal("expected unknown target to be rejected")
+	}
+	if _, err := NewTargetProvider("s3://", Config{}); err == nil {
+		t.Fatal("expected s3 target without bucket to be rejected")
+	}
+	if _, err := NewTargetProvider("local:/backups", Config{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
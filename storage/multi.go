@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+	"sort"
+)
+
+// Target is one member of a replicated provider.
+type Target struct {
+	Name     string
+	Provider Provider
+	// Retention overrides the retention policy on this target where set.
+	Retention RetentionPolicy
+}
+
+// multiProvider replicates backups to several targets. Saves go to every
+// target, listings are merged and fetches are served by the first target
+// that has the file.
+type multiProvider struct {
+	targets []Target
+}
+
+// NewMultiProvider returns a provider that replicates to targets, in order
+// of preference for fetches.
+func NewMultiProvider(targets []Target) Provider {
+	return &multiProvider{targets: targets}
+}
+
+func (p *multiProvider) EnsureDatabase(database string) error {
+	var problems []error
+	for _, target := range p.targets {
+		if err := target.Provider.EnsureDatabase(database); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// PartialSaveError reports a save that reached only some of the targets.
+// Saved holds the targets that stored the file.
+type PartialSaveError struct {
+	Saved Provider
+	Err   error
+}
+
+func (e *PartialSaveError) Error() string { return e.Err.Error() }
+
+func (e *PartialSaveError) Unwrap() error { return e.Err }
+
+// Save stores localPath on every target. A failing target does not keep the
+// others from receiving the backup; when some targets fail a
+// *PartialSaveError is returned.
+func (p *multiProvider) Save(database, filename, localPath string) error {
+	var (
+		problems []error
+		saved    []Target
+	)
+	for i, target := range p.targets {
+		source := localPath
+		if i < len(p.targets)-1 {
+			// Saving may move the file, so all but the last target get a copy.
+			copyPath, err := tempCopy(localPath)
+			if err != nil {
+				return err
+			}
+			source = copyPath
+		}
+		if err := target.Provider.Save(database, filename, source); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+		} else {
+			saved = append(saved, target)
+		}
+		if source != localPath {
+			os.Remove(source)
+		}
+	}
+	if len(problems) > 0 && len(saved) > 0 {
+		return &PartialSaveError{Saved: NewMultiProvider(saved), Err: errors.Join(problems...)}
+	}
+	return errors.Join(problems...)
+}
+
+// Holding returns the part of p whose targets store filename under database,
+// so metadata is not written next to artifacts a target never received.
+// Providers that do not replicate are returned as they are.
+func Holding(p Provider, database, filename string) (Provider, error) {
+	multi, ok := p.(*multiProvider)
+	if !ok {
+		return p, nil
+	}
+	var holding []Target
+	for _, target := range multi.targets {
+		files, err := target.Provider.List(database)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target.Name, err)
+		}
+		if slices.ContainsFunc(files, func(file FileInfo) bool { return file.Name == filename }) {
+			holding = append(holding, target)
+		}
+	}
+	if len(holding) == 0 {
+		return nil, fmt.Errorf("%s not found: %w", filename, fs.ErrNotExist)
+	}
+	return NewMultiProvider(holding), nil
+}
+
+// List merges the listings of all targets. Targets that cannot be listed are
+// logged and left out unless none of them can be listed.
+func (p *multiProvider) List(database string) ([]FileInfo, error) {
+	seen := map[string]bool{}
+	var (
+		files    []FileInfo
+		problems []error
+	)
+	for _, target := range p.targets {
+		listed, err := target.Provider.List(database)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+			continue
+		}
+		for _, file := range listed {
+			if !seen[file.Name] {
+				seen[file.Name] = true
+				files = append(files, file)
+			}
+		}
+	}
+	if len(problems) == len(p.targets) {
+		return nil, errors.Join(problems...)
+	}
+	for _, problem := range problems {
+		if !errors.Is(problem, fs.ErrNotExist) {
+			log.Println("list backups error:", problem)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func (p *multiProvider) Children(database string) ([]string, error) {
+	seen := map[string]bool{}
+	var (
+		children []string
+		problems []error
+	)
+	for _, target := range p.targets {
+		listed, err := target.Provider.Children(database)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+			continue
+		}
+		for _, child := range listed {
+			if !seen[child] {
+				seen[child] = true
+				children = append(children, child)
+			}
+		}
+	}
+	if len(problems) == len(p.targets) {
+		return nil, errors.Join(problems...)
+	}
+	sort.Strings(children)
+	return children, nil
+}
+
+// Fetch returns the file from the first target that can provide it.
+func (p *multiProvider) Fetch(database, filename string) (string, func() error, error) {
+	var problems []error
+	for _, target := range p.targets {
+		localPath, cleanup, err := target.Provider.Fetch(database, filename)
+		if err == nil {
+			return localPath, cleanup, nil
+		}
+		problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+	}
+	return "", nil, errors.Join(problems...)
+}
+
+// Delete removes the file from every target that has it.
+func (p *multiProvider) Delete(database, filename string) error {
+	var problems []error
+	deleted := false
+	for _, target := range p.targets {
+		err := target.Provider.Delete(database, filename)
+		switch {
+		case err == nil:
+			deleted = true
+		case !errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+		}
+	}
+	if !deleted && len(problems) == 0 {
+		return fmt.Errorf("delete %s: %w", filename, fs.ErrNotExist)
+	}
+	return errors.Join(problems...)
+}
+
+// SetTags tags the file on every target that supports tags.
+func (p *multiProvider) SetTags(database, filename string, tags map[string]string) error {
+	var problems []error
+	for _, target := range p.targets {
+		if tagger, ok := target.Provider.(Tagger); ok {
+			if err := tagger.SetTags(database, filename, tags); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %w", target.Name, err))
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// tempCopy copies localPath to a new temporary file.
+func tempCopy(localPath string) (string, error) {
+	tempFile, err := os.CreateTemp("", "replica-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	tempFile.Close()
+	if err := copyFile(localPath, tempPath); err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return tempPath, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMultiProviderReplicatesAndAppliesTargetRetention(t *testing.T) {
+	localPath := t.TempDir()
+	offsitePath := t.TempDir()
+	provider, err := NewProvider("local,offsite", Config{
+		Local: LocalConfig{BasePath: localPath},
+		Targets: map[string]TargetConfig{
+			"local":   {Retention: RetentionPolicy{Daily: 3 * 24 * time.Hour}},
+			"offsite": {Spec: "local:" + offsitePath},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider returned error: %v", err)
+	}
+	if err := provider.EnsureDatabase("users"); err != nil {
+		t.Fatalf("ensure database: %v", err)
+	}
+
+	now := time.Now()
+	oldName := "file_daily_" + now.Add(-5*24*time.Hour).Format(time.RFC3339) + ".dump"
+	newName := "file_daily_" + now.Add(-1*24*time.Hour).Format(time.RFC3339) + ".dump"
+	for _, name := range []string{oldName, newName} {
+		tempPath := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(tempPath, []byte(name), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		if err := provider.Save("users", name, tempPath); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+	for _, basePath := range []string{localPath, offsitePath} {
+		for _, name := range []string{oldName, newName} {
+			if _, err := os.Stat(filepath.Join(basePath, "users", name)); err != nil {
+				t.Fatalf("expected %s in %s: %v", name, basePath, err)
+			}
+		}
+	}
+
+	if err := Cleanup(provider, "users", now, RetentionPolicy{Daily: 7 * 24 * time.Hour}); err != nil {
+		t.Fatalf("Cleanup returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(localPath, "users", oldName)); !os.IsNotExist(err) {
+		t.Fatalf("expected local retention to remove %s, got %v", oldName, err)
+	}
+	if _, err := os.Stat(filepath.Join(offsitePath, "users", oldName)); err != nil {
+		t.Fatalf("expected offsite copy to be kept: %v", err)
+	}
+
+	files, err := provider.List("users")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected merged listing of 2 files, got %+v", files)
+	}
+	fetched, cleanup, err := provider.Fetch("users", oldName)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	defer cleanup()
+	if filepath.Dir(fetched) != filepath.Join(offsitePath, "users") {
+		t.Fatalf("expected fetch from offsite copy, got %s", fetched)
+	}
+}
+
+func TestMultiProviderReportsPartialSaves(t *testing.T) {
+	localPath := t.TempDir()
+	remotePath := t.TempDir()
+	provider := NewMultiProvider([]Target{
+		{Name: "local", Provider: NewLocalProvider(localPath)},
+		{Name: "s3", Provider: &flakyProvider{Provider: NewLocalProvider(remotePath), down: true}},
+	})
+
+	tempPath := filepath.Join(t.TempDir(), "file_daily.dump")
+	if err := os.WriteFile(tempPath, []byte("backup"), 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	err := provider.Save("users", "file_daily.dump", tempPath)
+	var partial *PartialSaveError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a partial save error, got %v", err)
+	}
+
+	if err := SaveManifest(partial.Saved, "users", Manifest{Database: "users", Filename: "file_daily.dump"}); err != nil {
+		t.Fatalf("save manifest: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(localPath, "users", ManifestName("file_daily.dump"))); err != nil {
+		t.Fatalf("expected manifest on the target that stored the backup: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(remotePath, "users", ManifestName("file_daily.dump"))); !os.IsNotExist(err) {
+		t.Fatalf("expected no manifest on the failed target, got %v", err)
+	}
+}
+
+func TestTargetSpecResolvesNamedTargets(t *testing.T) {
+	cfg := Config{Targets: map[string]TargetConfig{
+		"archive": {Spec: "s3://archive/backups"},
+		"s3":      {},
+	}}
+
+	cases := map[string]string{
+		"archive":       "s3://archive/backups",
+		"s3":            "s3",
+		"local:/data":   "local:/data",
+		"s3://other/db": "s3://other/db",
+	}
+	for name, want := range cases {
+		if got := TargetSpec(name, cfg); got != want {
+			t.Fatalf("TargetSpec(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
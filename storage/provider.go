@@ -5,15 +5,25 @@ import "time"
 // FileInfo represents a backup artifact in storage.
 type FileInfo struct {
 	Name     string
+	Size     int64
 	Modified time.Time
 }
 
 // Provider describes the capabilities required by the controller to
-// persist and retrieve backups.
+// persist and retrieve backups. Database keys may be nested with "/" (for
+// example "service/dbname"); List returns only the files stored directly
+// under a key and Children the nested keys one level below it.
 type Provider interface {
 	EnsureDatabase(database string) error
 	Save(database, filename, localPath string) error
 	List(database string) ([]FileInfo, error)
+	Children(database string) ([]string, error)
 	Fetch(database, filename string) (localPath string, cleanup func() error, err error)
 	Delete(database, filename string) error
 }
+
+// Tagger is implemented by providers that can label stored objects, such as
+// S3 object tags. Tags replace any tags set before.
+type Tagger interface {
+	SetTags(database, filename string, tags map[string]string) error
+}
@@ -59,6 +59,15 @@ func (p *s3Provider) Save(database, filename, localPath string) error {
 	return nil
 }
 
+func (p *s3Provider) SetTags(database, filename string, tags map[string]string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if err := p.client.PutObjectTagging(ctx, p.bucket, p.objectKey(database, filename), tags); err != nil {
+		return fmt.Errorf("tag object: %w", err)
+	}
+	return nil
+}
+
 func (p *s3Provider) List(database string) ([]FileInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
@@ -66,7 +75,7 @@ func (p *s3Provider) List(database string) ([]FileInfo, error) {
 	token := ""
 	var files []FileInfo
 	for {
-		output, err := p.client.ListObjectsV2(ctx, p.bucket, prefix, token)
+		output, err := p.client.ListObjectsV2Delimited(ctx, p.bucket, prefix, "/", token)
 		if err != nil {
 			return nil, fmt.Errorf("list objects: %w", err)
 		}
@@ -75,7 +84,7 @@ func (p *s3Provider) List(database string) ([]FileInfo, error) {
 			if name == "" {
 				continue
 			}
-			files = append(files, FileInfo{Name: name, Modified: object.LastModified})
+			files = append(files, FileInfo{Name: name, Size: object.Size, Modified: object.LastModified})
 		}
 		if !output.IsTruncated || output.NextContinuationToken == "" {
 			break
@@ -85,6 +94,30 @@ func (p *s3Provider) List(database string) ([]FileInfo, error) {
 	return files, nil
 }
 
+func (p *s3Provider) Children(database string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	prefix := p.databasePrefix(database)
+	token := ""
+	var children []string
+	for {
+		output, err := p.client.ListObjectsV2Delimited(ctx, p.bucket, prefix, "/", token)
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+		for _, commonPrefix := range output.CommonPrefixes {
+			if name := path.Base(strings.TrimPrefix(commonPrefix, prefix)); name != "" && name != "." {
+				children = append(children, name)
+			}
+		}
+		if !output.IsTruncated || output.NextContinuationToken == "" {
+			break
+		}
+		token = output.NextContinuationToken
+	}
+	return children, nil
+}
+
 func (p *s3Provider) Fetch(database, filename string) (string, func() error, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	backupPrefix  = "file_"
+	backupSuffix  = ".dump"
+	globalsPrefix = "globals_"
+	globalsSuffix = ".sql"
+
+	// PreRestoreType is the backup class of safety snapshots taken before a
+	// restore. Selectors skip it unless it is asked for explicitly.
+	PreRestoreType = "pre-restore"
+)
+
+// timestampLayouts are accepted by selectors; layouts without a zone are
+// interpreted in the local time zone.
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseBackupName extracts the type and creation time from a backup named
+// file_<type>_<RFC3339>.dump.
+func ParseBackupName(name string) (string, time.Time, bool) {
+	if !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+		return "", time.Time{}, false
+	}
+	backupType, stamp, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix), "_")
+	if !ok {
+		return "", time.Time{}, false
+	}
+	created, err := time.Parse(time.RFC3339, stamp)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return backupType, created, true
+}
+
+// artifactCreated returns the creation time in the name of a backup or of its
+// globals companion, globals_<type>_<RFC3339>.sql.
+func artifactCreated(name string) (time.Time, bool) {
+	if strings.HasPrefix(name, globalsPrefix) && strings.HasSuffix(name, globalsSuffix) {
+		name = backupPrefix + strings.TrimSuffix(strings.TrimPrefix(name, globalsPrefix), globalsSuffix) + backupSuffix
+	}
+	_, created, ok := ParseBackupName(name)
+	return created, ok
+}
+
+// ParseTimestamp parses a point in time given on the command line.
+func ParseTimestamp(value string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, use RFC3339 such as 2025-07-04T09:00:00Z", value)
+}
+
+// IsSelector reports whether name is a selector rather than a filename.
+func IsSelector(name string) bool {
+	return name == "latest" ||
+		strings.HasPrefix(name, "latest-") ||
+		strings.HasPrefix(name, "before:") ||
+		strings.HasPrefix(name, "at:")
+}
+
+// SelectBackup picks a backup from files. Supported selectors are "latest",
+// "latest-<type>", "before:<time>" for the newest backup strictly before the
+// given time and "at:<time>" for the newest one at or before it. Only
+// "latest-pre-restore" selects safety snapshots.
+func SelectBackup(files []FileInfo, selector string) (string, error) {
+	var (
+		backupType string
+		limit      time.Time
+		inclusive  bool
+	)
+	switch {
+	case selector == "latest":
+	case strings.HasPrefix(selector, "latest-"):
+		backupType = strings.TrimPrefix(selector, "latest-")
+	case strings.HasPrefix(selector, "before:"), strings.HasPrefix(selector, "at:"):
+		kind, value, _ := strings.Cut(selector, ":")
+		parsed, err := ParseTimestamp(value)
+		if err != nil {
+			return "", err
+		}
+		limit, inclusive = parsed, kind == "at"
+	default:
+		return "", fmt.Errorf("unknown selector %q", selector)
+	}
+
+	var (
+		best    string
+		bestAt  time.Time
+		matched bool
+	)
+	for _, file := range files {
+		fileType, created, ok := ParseBackupName(file.Name)
+		if !ok || (backupType != "" && fileType != backupType) || (backupType == "" && fileType == PreRestoreType) {
+			continue
+		}
+		if !limit.IsZero() && (created.After(limit) || (!inclusive && created.Equal(limit))) {
+			continue
+		}
+		if !matched || created.After(bestAt) {
+			best, bestAt, matched = file.Name, created, true
+		}
+	}
+	if !matched {
+		return "", errors.New("no backup matches " + selector)
+	}
+	return best, nil
+}
+
+// ResolveBackup returns name unchanged unless it is a selector, in which
+// case the matching backup of database is looked up.
+func ResolveBackup(p Provider, database, name string) (string, error) {
+	if !IsSelector(name) {
+		return name, nil
+	}
+	files, err := p.List(database)
+	if err != nil {
+		return "", err
+	}
+	return SelectBackup(files, name)
+}
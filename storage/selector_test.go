@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBackupName(t *testing.T) {
+	backupType, created, ok := ParseBackupName("file_daily_2025-07-04T09:00:00Z.dump")
+	if !ok || backupType != "daily" || !created.Equal(time.Date(2025, 7, 4, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected result: %q %v %v", backupType, created, ok)
+	}
+	for _, name := range []string{"globals_daily_2025-07-04T09:00:00Z.sql", "file_daily_2025-07-04T09:00:00Z.dump.manifest.json", "file_daily.dump"} {
+		if _, _, ok := ParseBackupName(name); ok {
+			t.Fatalf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestSelectBackup(t *testing.T) {
+	files := []FileInfo{
+		{Name: "file_daily_2025-07-04T09:00:00Z.dump"},
+		{Name: "file_daily_2025-07-04T09:00:00Z.dump.manifest.json"},
+		{Name: "file_weekly_2025-07-05T03:00:00Z.dump"},
+		{Name: "file_manual_2025-07-04T12:30:00+02:00.dump"},
+		{Name: "file_daily_2025-07-03T21:00:00Z.dump"},
+		{Name: "globals_daily_2025-07-06T09:00:00Z.sql"},
+		{Name: "file_pre-restore_2025-07-06T10:00:00Z.dump"},
+	}
+
+	tests := map[string]string{
+		"latest":                      "file_weekly_2025-07-05T03:00:00Z.dump",
+		"latest-daily":                "file_daily_2025-07-04T09:00:00Z.dump",
+		"before:2025-07-04T10:31:00Z": "file_manual_2025-07-04T12:30:00+02:00.dump",
+		"before:2025-07-04T09:00:00Z": "file_daily_2025-07-03T21:00:00Z.dump",
+		"at:2025-07-04T09:00:00Z":     "file_daily_2025-07-04T09:00:00Z.dump",
+		"latest-pre-restore":          "file_pre-restore_2025-07-06T10:00:00Z.dump",
+	}
+	for selector, want := range tests {
+		got, err := SelectBackup(files, selector)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", selector, err)
+		}
+		if got != want {
+			t.Fatalf("%s: expected %s, got %s", selector, want, got)
+		}
+	}
+
+	for _, selector := range []string{"latest-monthly", "before:2025-01-01", "at:yesterday"} {
+		if _, err := SelectBackup(files, selector); err == nil {
+			t.Fatalf("%s: expected error", selector)
+		}
+	}
+}
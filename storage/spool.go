@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	partialSuffix   = ".partial"
+	minSpoolBackoff = time.Minute
+	maxSpoolBackoff = time.Hour
+)
+
+// SpoolConfig configures the local outbox of remote targets. A zero MaxBytes
+// disables spooling.
+type SpoolConfig struct {
+	Path     string
+	MaxBytes int64
+}
+
+// PendingFile is a backup waiting in a spool for its upload.
+type PendingFile struct {
+	Database string
+	Filename string
+	Size     int64
+	Spooled  time.Time
+}
+
+// SpoolStats summarises the state of a spool.
+type SpoolStats struct {
+	Files    int
+	Bytes    int64
+	Oldest   time.Time
+	Failures int64
+}
+
+// Spool wraps a remote provider. Uploads that fail are kept in a local
+// directory and retried by Flush, so a backup is not lost while the remote
+// storage is unavailable.
+type Spool struct {
+	Name     string
+	remote   Provider
+	path     string
+	maxBytes int64
+
+	mu      sync.Mutex
+	retries map[string]spoolRetry
+	// spooling serializes the size check and the move into the spool.
+	spooling sync.Mutex
+}
+
+// spoolFailures counts failed uploads per spool name. It outlives the spools
+// so the count survives a configuration reload, which builds new ones.
+var spoolFailures = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: map[string]int64{}}
+
+func (s *Spool) countFailure() {
+	spoolFailures.Lock()
+	spoolFailures.counts[s.Name]++
+	spoolFailures.Unlock()
+}
+
+type spoolRetry struct {
+	next    time.Time
+	backoff time.Duration
+}
+
+// NewSpool returns remote wrapped with a spool in path holding at most
+// maxBytes.
+func NewSpool(name string, remote Provider, path string, maxBytes int64) *Spool {
+	return &Spool{Name: name, remote: remote, path: path, maxBytes: maxBytes, retries: map[string]spoolRetry{}}
+}
+
+func (s *Spool) EnsureDatabase(database string) error {
+	return s.remote.EnsureDatabase(database)
+}
+
+// Save uploads localPath and spools it when the upload fails. The upload
+// error is only returned when the file does not fit into the spool.
+func (s *Spool) Save(database, filename, localPath string) error {
+	err := s.remote.Save(database, filename, localPath)
+	if err == nil {
+		return nil
+	}
+	s.countFailure()
+	if spoolErr := s.spool(database, filename, localPath); spoolErr != nil {
+		return errors.Join(err, fmt.Errorf("spool: %w", spoolErr))
+	}
+	log.Printf("upload of %s/%s to %s failed, kept in spool for retry: %v", database, filename, s.Name, err)
+	return nil
+}
+
+func (s *Spool) List(database string) ([]FileInfo, error) {
+	return s.remote.List(database)
+}
+
+func (s *Spool) Children(database string) ([]string, error) {
+	return s.remote.Children(database)
+}
+
+// Fetch serves files that are still waiting in the spool when the remote
+// storage cannot provide them.
+func (s *Spool) Fetch(database, filename string) (string, func() error, error) {
+	localPath, cleanup, err := s.remote.Fetch(database, filename)
+	if err == nil {
+		return localPath, cleanup, nil
+	}
+	spooled := s.spoolPath(database, filename)
+	if _, statErr := os.Stat(spooled); statErr == nil {
+		return spooled, func() error { return nil }, nil
+	}
+	return "", nil, err
+}
+
+// Delete removes the file from the spool and from the remote storage, as a
+// copy may be left in both places. It fails when neither had the file.
+func (s *Spool) Delete(database, filename string) error {
+	spoolErr := os.Remove(s.spoolPath(database, filename))
+	if spoolErr != nil && !errors.Is(spoolErr, fs.ErrNotExist) {
+		return spoolErr
+	}
+	s.mu.Lock()
+	delete(s.retries, database+"/"+filename)
+	s.mu.Unlock()
+	if err := s.remote.Delete(database, filename); err != nil && (spoolErr != nil || !errors.Is(err, fs.ErrNotExist)) {
+		return err
+	}
+	return nil
+}
+
+func (s *Spool) SetTags(database, filename string, tags map[string]string) error {
+	tagger, ok := s.remote.(Tagger)
+	if !ok {
+		return nil
+	}
+	return tagger.SetTags(database, filename, tags)
+}
+
+// Pending lists the files waiting in the spool, oldest first.
+func (s *Spool) Pending() ([]PendingFile, error) {
+	var pending []PendingFile
+	err := filepath.WalkDir(s.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, partialSuffix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(s.path, path)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, PendingFile{
+			Database: filepath.ToSlash(filepath.Dir(relative)),
+			Filename: entry.Name(),
+			Size:     info.Size(),
+			Spooled:  info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list spool: %w", err)
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		if !pending[i].Spooled.Equal(pending[j].Spooled) {
+			return pending[i].Spooled.Before(pending[j].Spooled)
+		}
+		return pending[i].Database+"/"+pending[i].Filename < pending[j].Database+"/"+pending[j].Filename
+	})
+	return pending, nil
+}
+
+// Stats reports the pending files and the number of failed uploads through
+// spools of the same name since the start.
+func (s *Spool) Stats() (SpoolStats, error) {
+	pending, err := s.Pending()
+	if err != nil {
+		return SpoolStats{}, err
+	}
+	spoolFailures.Lock()
+	stats := SpoolStats{Files: len(pending), Failures: spoolFailures.counts[s.Name]}
+	spoolFailures.Unlock()
+	for _, file := range pending {
+		stats.Bytes += file.Size
+		if stats.Oldest.IsZero() || file.Spooled.Before(stats.Oldest) {
+			stats.Oldest = file.Spooled
+		}
+	}
+	return stats, nil
+}
+
+// Flush uploads the pending files that are due at now and returns how many
+// were uploaded. Files that fail again are retried with exponential backoff.
+func (s *Spool) Flush(now time.Time) (int, error) {
+	pending, err := s.Pending()
+	if err != nil {
+		return 0, err
+	}
+	flushed := 0
+	var problems []error
+	for _, file := range pending {
+		key := file.Database + "/" + file.Filename
+		s.mu.Lock()
+		retry := s.retries[key]
+		s.mu.Unlock()
+		if now.Before(retry.next) {
+			continue
+		}
+
+		if err := s.upload(file); err != nil {
+			retry.backoff = min(max(2*retry.backoff, minSpoolBackoff), maxSpoolBackoff)
+			retry.next = now.Add(retry.backoff)
+			s.mu.Lock()
+			s.retries[key] = retry
+			s.mu.Unlock()
+			s.countFailure()
+			problems = append(problems, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		s.mu.Lock()
+		delete(s.retries, key)
+		s.mu.Unlock()
+		flushed++
+	}
+	return flushed, errors.Join(problems...)
+}
+
+// upload sends a spooled file to the remote storage and removes it from the
+// spool.
+func (s *Spool) upload(file PendingFile) error {
+	spooled := s.spoolPath(file.Database, file.Filename)
+	copyPath, err := tempCopy(spooled)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(copyPath)
+	if err := s.remote.EnsureDatabase(file.Database); err != nil {
+		return err
+	}
+	if err := s.remote.Save(file.Database, file.Filename, copyPath); err != nil {
+		return err
+	}
+	return os.Remove(spooled)
+}
+
+// spool moves localPath into the spool unless that would exceed its size.
+func (s *Spool) spool(database, filename, localPath string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	s.spooling.Lock()
+	defer s.spooling.Unlock()
+	stats, err := s.Stats()
+	if err != nil {
+		return err
+	}
+	if stats.Bytes+info.Size() > s.maxBytes {
+		return fmt.Errorf("spool is full: %d of %d bytes used", stats.Bytes, s.maxBytes)
+	}
+
+	destPath := s.spoolPath(database, filename)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return fmt.Errorf("create spool directory: %w", err)
+	}
+	if err := copyFile(localPath, destPath+partialSuffix); err != nil {
+		os.Remove(destPath + partialSuffix)
+		return err
+	}
+	if err := os.Rename(destPath+partialSuffix, destPath); err != nil {
+		return fmt.Errorf("move into spool: %w", err)
+	}
+	// The caller hands the file over like to any other provider.
+	return os.Remove(localPath)
+}
+
+func (s *Spool) spoolPath(database, filename string) string {
+	return filepath.Join(s.path, filepath.FromSlash(database), filename)
+}
+
+// Spools returns the spools used by p, including those of replication
+// targets.
+func Spools(p Provider) []*Spool {
+	switch provider := p.(type) {
+	case *Spool:
+		return []*Spool{provider}
+	case *multiProvider:
+		var spools []*Spool
+		for _, target := range provider.targets {
+			spools = append(spools, Spools(target.Provider)...)
+		}
+		return spools
+	default:
+		return nil
+	}
+}
+
+// WithoutSpool returns p with its spools removed, so that saves fail when
+// the remote storage does. Health checks use it to probe the real targets.
+func WithoutSpool(p Provider) Provider {
+	switch provider := p.(type) {
+	case *Spool:
+		return provider.remote
+	case *multiProvider:
+		targets := make([]Target, len(provider.targets))
+		for i, target := range provider.targets {
+			target.Provider = WithoutSpool(target.Provider)
+			targets[i] = target
+		}
+		return NewMultiProvider(targets)
+	default:
+		return p
+	}
+}
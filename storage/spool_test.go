@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyProvider fails every save while down.
+type flakyProvider struct {
+	Provider
+	down bool
+}
+
+func (p *flakyProvider) Save(database, filename, localPath string) error {
+	if p.down {
+		return errors.New("remote unavailable")
+	}
+	return p.Provider.Save(database, filename, localPath)
+}
+
+func TestSpoolKeepsFailedUploadsAndFlushesThem(t *testing.T) {
+	remotePath := t.TempDir()
+	remote := &flakyProvider{Provider: NewLocalProvider(remotePath), down: true}
+	spool := NewSpool("s3", remote, t.TempDir(), 10)
+	// The failure count outlives spools, so earlier runs must not add to it.
+	spoolFailures.Lock()
+	delete(spoolFailures.counts, "s3")
+	spoolFailures.Unlock()
+
+	writeTemp := func(name, content string) string {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	if err := spool.Save("users/app", "file_daily.dump", writeTemp("file_daily.dump", "backup")); err != nil {
+		t.Fatalf("expected failed upload to be spooled, got %v", err)
+	}
+	if err := spool.Save("users", "file_manual.dump", writeTemp("file_manual.dump", "too large")); err == nil {
+		t.Fatal("expected upload exceeding the spool size to fail")
+	}
+
+	pending, err := spool.Pending()
+	if err != nil {
+		t.Fatalf("Pending returned error: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Database != "users/app" || pending[0].Filename != "file_daily.dump" || pending[0].Size != 6 {
+		t.Fatalf("unexpected pending files: %+v", pending)
+	}
+	if localPath, _, err := spool.Fetch("users/app", "file_daily.dump"); err != nil || filepath.Dir(localPath) == remotePath {
+		t.Fatalf("expected fetch from spool, got %s, %v", localPath, err)
+	}
+
+	now := time.Now()
+	if flushed, err := spool.Flush(now); flushed != 0 || err == nil {
+		t.Fatalf("expected flush to fail while remote is down, got %d, %v", flushed, err)
+	}
+	remote.down = false
+	if flushed, err := spool.Flush(now.Add(30 * time.Second)); flushed != 0 || err != nil {
+		t.Fatalf("expected retry to wait for the backoff, got %d, %v", flushed, err)
+	}
+	if flushed, err := spool.Flush(now.Add(time.Minute)); flushed != 1 || err != nil {
+		t.Fatalf("expected spooled file to be uploaded, got %d, %v", flushed, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(remotePath, "users", "app", "file_daily.dump")); err != nil {
+		t.Fatalf("expected uploaded file at remote: %v", err)
+	}
+	stats, err := spool.Stats()
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	if stats.Files != 0 || stats.Bytes != 0 || stats.Failures != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	reloaded := NewSpool("s3", remote, t.TempDir(), 10)
+	if stats, err := reloaded.Stats(); err != nil || stats.Failures != 3 {
+		t.Fatalf("expected failures to survive a reload, got %+v, %v", stats, err)
+	}
+}
+
+func TestWithoutSpoolExposesRemoteFailures(t *testing.T) {
+	remote := &flakyProvider{Provider: NewLocalProvider(t.TempDir()), down: true}
+	provider := NewMultiProvider([]Target{
+		{Name: "local", Provider: NewLocalProvider(t.TempDir())},
+		{Name: "s3", Provider: NewSpool("s3", remote, t.TempDir(), 1<<20)},
+	})
+
+	probe := filepath.Join(t.TempDir(), "probe.tmp")
+	if err := os.WriteFile(probe, []byte("probe"), 0o600); err != nil {
+		t.Fatalf("write probe: %v", err)
+	}
+	if err := WithoutSpool(provider).Save("users", "probe.tmp", probe); err == nil {
+		t.Fatal("expected the failing remote to be reported")
+	}
+}
+
+func TestSpoolKeepsConcurrentSavesWithinItsSize(t *testing.T) {
+	remote := &flakyProvider{Provider: NewLocalProvider(t.TempDir()), down: true}
+	spool := NewSpool("concurrent", remote, t.TempDir(), 10)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		path := filepath.Join(t.TempDir(), "backup")
+		if err := os.WriteFile(path, []byte("backup"), 0o600); err != nil {
+			t.Fatalf("write backup: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = spool.Save("users", fmt.Sprintf("file_daily_%d.dump", i), path)
+		}()
+	}
+	wg.Wait()
+
+	stats, err := spool.Stats()
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	if stats.Files != 1 || stats.Bytes > 10 {
+		t.Fatalf("expected the spool to stay within its size, got %+v", stats)
+	}
+}
+
+func TestSpoolDeletesSpooledAndRemoteCopies(t *testing.T) {
+	remotePath := t.TempDir()
+	spoolPath := t.TempDir()
+	spool := NewSpool("delete", NewLocalProvider(remotePath), spoolPath, 1<<20)
+
+	for _, path := range []string{
+		filepath.Join(remotePath, "users", "file_daily.dump"),
+		filepath.Join(spoolPath, "users", "file_daily.dump"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("backup"), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	if err := spool.Delete("users", "file_daily.dump"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	for _, path := range []string{remotePath, spoolPath} {
+		if _, err := os.Stat(filepath.Join(path, "users", "file_daily.dump")); !os.IsNotExist(err) {
+			t.Fatalf("expected copy in %s to be deleted, got %v", path, err)
+		}
+	}
+	if err := spool.Delete("users", "file_daily.dump"); err == nil {
+		t.Fatal("expected deleting a missing file to fail")
+	}
+}
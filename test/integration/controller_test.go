@@ -302,7 +302,12 @@ func runController(t *testing.T, dir, container string, args ...string) {
 func latestBackup(t *testing.T, dir, container, path string) string {
 	t.Helper()
 	output := runDockerCommand(t, dir, "exec", "-u", "postgres", container, "ls", "-1", path)
-	lines := strings.Split(strings.TrimSpace(output), "\n")
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if !strings.HasSuffix(line, ".manifest.json") {
+			lines = append(lines, line)
+		}
+	}
 	sort.Strings(lines)
 	if len(lines) == 0 || lines[0] == "" {
 		t.Fatalf("no backups found in %s", path)
@@ -352,7 +357,7 @@ func listS3Backups(t *testing.T, client *s3client.Client, bucket, prefix, databa
 		}
 		for _, object := range output.Objects {
 			base := pathBase(object.Key)
-			if base == "" {
+			if base == "" || strings.HasSuffix(base, ".manifest.json") {
 				continue
 			}
 			results = append(results, base)
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Database string    `json:"database"`
+	Filename string    `json:"filename"`
+	User     string    `json:"user"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// Audit appends entry to the JSON lines file at path, filling in the time
+// and the user running the command.
+func Audit(path string, entry AuditEntry) error {
+	entry.Time = time.Now().UTC()
+	entry.User = os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		entry.User = current.Username
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode audit entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// Clone restores filename, a backup name or selector of source, into another
+// database. Targets marked as protected are refused.
+func Clone(provider storage.Provider, source config.Database, filename string, target config.Database, options RestoreOptions) RestoreResult {
+	backup, name := restoreSource(source, filename)
+	result := RestoreResult{Database: target.Name, Filename: name}
+
+	if target.Protected {
+		result.Err = errors.New("target is protected, refusing to overwrite it")
+		return result
+	}
+
+	resolved, err := storage.ResolveBackup(provider, backup.key, name)
+	if err != nil {
+		result.Err = fmt.Errorf("resolve backup: %w", err)
+		return result
+	}
+	result.Filename = backup.key + "/" + resolved
+	if resolved != name {
+		fmt.Printf("%s: %s resolved to %s\n", backup.key, name, resolved)
+	}
+
+	if options.CreateDatabase {
+		if err := createDatabase(target); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	if !options.SkipSafetySnapshot {
+		// Snapshots of URL targets have no service of their own and are kept
+		// with the source backups.
+		service, key, policy := source.Name, backup.key, source.Retention.Policy()
+		if !strings.Contains(target.Name, "://") {
+			service, _, _ = strings.Cut(target.Name, "/")
+			key, policy = target.Name, target.Retention.Policy()
+		}
+		snapshot, err := safetySnapshot(provider, key, target, policy)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Rollback = "./controller restore --source " + service + " --target " + rollbackTarget(target) + " " + listedName(service, key, snapshot)
+	}
+
+	if options.WithGlobals {
+		if err := applyGlobals(provider, source.Name, target, resolved); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	result.Err = restoreInto(provider, backup.key, target, resolved, options, cloneArgs(options)...)
+	return result
+}
+
+// cloneArgs returns the pg_restore arguments a clone adds to options. The
+// target may lack objects of the source, so drops must not fail; data-only
+// clones drop nothing, and pg_restore rejects --if-exists without -c.
+func cloneArgs(options RestoreOptions) []string {
+	if options.DataOnly {
+		return nil
+	}
+	return []string{"--if-exists"}
+}
+
+// rollbackTarget returns the --target argument that restores target. The
+// name of a URL target is redacted, so the URL is shown with a placeholder
+// for the password instead.
+func rollbackTarget(target config.Database) string {
+	if target.URL == "" || !strings.Contains(target.Name, "://") {
+		return target.Name
+	}
+	return "'" + target.PlaceholderURL("<password>") + "'"
+}
+
+// createDatabase creates the target database unless it already exists.
+func createDatabase(target config.Database) error {
+	maintenance := target.ForDatabase("postgres")
+	exists, err := queryDatabase(maintenance, "SELECT 1 FROM pg_database WHERE datname = "+quoteLiteral(target.Database))
+	if err != nil {
+		return fmt.Errorf("check target database: %w", err)
+	}
+	if exists == "1" {
+		return nil
+	}
+	if _, err := queryDatabase(maintenance, "CREATE DATABASE "+quoteIdentifier(target.Database)); err != nil {
+		return fmt.Errorf("create target database: %w", err)
+	}
+	fmt.Println("created database", target.Database)
+	return nil
+}
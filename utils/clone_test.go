@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCloneArgs(t *testing.T) {
+	if args := cloneArgs(RestoreOptions{}); !slices.Equal(args, []string{"--if-exists"}) {
+		t.Fatalf("unexpected arguments for a full clone: %v", args)
+	}
+	if args := cloneArgs(RestoreOptions{DataOnly: true}); len(args) != 0 {
+		t.Fatalf("expected no --if-exists for a data-only clone, got %v", args)
+	}
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// backupSource is a single logical database together with the storage key
+// its backups are kept under.
+type backupSource struct {
+	key      string
+	database config.Database
+}
+
+// backupSources expands a configured database into the logical databases
+// to dump. In cluster mode every matching database on the server is stored
+// under "<service>/<dbname>".
+func backupSources(database config.Database) ([]backupSource, error) {
+	if !database.Cluster.Enabled {
+		return []backupSource{{key: database.Name, database: database}}, nil
+	}
+
+	output, err := queryDatabase(database, "SELECT datname FROM pg_database WHERE NOT datistemplate AND datallowconn ORDER BY datname")
+	if err != nil {
+		return nil, fmt.Errorf("list cluster databases: %w", err)
+	}
+
+	var sources []backupSource
+	for _, name := range strings.Split(output, "\n") {
+		name = strings.TrimSpace(name)
+		if name == "" || !database.Cluster.Matches(name) {
+			continue
+		}
+		sources = append(sources, backupSource{
+			key:      database.Name + "/" + name,
+			database: database.ForDatabase(name),
+		})
+	}
+	return sources, nil
+}
+
+// restoreSource resolves a backup name as shown by List. Names of the form
+// "<dbname>/<file>" refer to a logical database dumped in cluster mode.
+func restoreSource(database config.Database, name string) (backupSource, string) {
+	dbname, filename, nested := strings.Cut(name, "/")
+	if !nested {
+		return backupSource{key: database.Name, database: database}, name
+	}
+	return backupSource{key: database.Name + "/" + dbname, database: database.ForDatabase(dbname)}, filename
+}
+
+// storedSources lists the storage keys holding backups of database. In
+// cluster mode these are the logical databases found in storage, so that
+// databases dropped from the server since can still be restored.
+func storedSources(provider storage.Provider, database config.Database) ([]backupSource, error) {
+	if !database.Cluster.Enabled {
+		return []backupSource{{key: database.Name, database: database}}, nil
+	}
+	children, err := provider.Children(database.Name)
+	if err != nil {
+		return nil, fmt.Errorf("list cluster backups: %w", err)
+	}
+	sources := make([]backupSource, 0, len(children))
+	for _, child := range children {
+		sources = append(sources, backupSource{
+			key:      database.Name + "/" + child,
+			database: database.ForDatabase(child),
+		})
+	}
+	return sources, nil
+}
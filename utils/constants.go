@@ -1,4 +0,0 @@
-package utils
-
-const BaseBackupDirectoryPath = "/var/lib/postgresql/backup/data"
-const IntervalInHours = 6
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"docker-postgres-backuper/archive"
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// DiffSchemas compares the schema of two backups of database. Both names
+// may be backup names or selectors.
+func DiffSchemas(provider storage.Provider, database config.Database, before, after string) ([]archive.SchemaChange, error) {
+	beforeObjects, err := schemaObjects(provider, database, before)
+	if err != nil {
+		return nil, err
+	}
+	afterObjects, err := schemaObjects(provider, database, after)
+	if err != nil {
+		return nil, err
+	}
+	return archive.DiffSchemas(beforeObjects, afterObjects), nil
+}
+
+func schemaObjects(provider storage.Provider, database config.Database, filename string) ([]archive.SchemaObject, error) {
+	source, name := restoreSource(database, filename)
+	resolved, err := storage.ResolveBackup(provider, source.key, name)
+	if err != nil {
+		return nil, fmt.Errorf("resolve backup %s: %w", filename, err)
+	}
+
+	localPath, cleanup, err := provider.Fetch(source.key, resolved)
+	if err != nil {
+		return nil, fmt.Errorf("fetch backup %s: %w", resolved, err)
+	}
+	defer removeFetched(cleanup)
+
+	schemaCommand := exec.Command("pg_restore", "-s", "-f", "-", localPath)
+	var stderr bytes.Buffer
+	schemaCommand.Stderr = &stderr
+	output, err := schemaCommand.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pg_restore -s %s: %w: %s", resolved, err, strings.TrimSpace(stderr.String()))
+	}
+	return archive.ParseSchema(bytes.NewReader(output))
+}
@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+const maxClockSkew = time.Minute
+
+var toolVersionPattern = regexp.MustCompile(`\(PostgreSQL\) (\d+)`)
+
+// Check is the outcome of a single preflight check.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// DoctorReport collects the results of all preflight checks.
+type DoctorReport struct {
+	Checks []Check `json:"checks"`
+}
+
+// Failed reports whether any check failed.
+func (r DoctorReport) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the human readable report.
+func (r DoctorReport) Print(w io.Writer) {
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "[%-4s] %s: %s\n", check.Status, check.Name, check.Detail)
+	}
+}
+
+func (r *DoctorReport) add(name, status, format string, args ...any) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Doctor verifies that every dependency of the controller is usable: client
+// tools, database connectivity, storage, temporary space and the clock.
+func Doctor(provider storage.Provider, databases []config.Database) DoctorReport {
+	var report DoctorReport
+
+	dumpMajor := checkTool(&report, "pg_dump")
+	restoreMajor := checkTool(&report, "pg_restore")
+	checkTool(&report, "psql")
+	// pg_dumpall is only needed for the globals companion dumps.
+	if slices.ContainsFunc(databases, func(database config.Database) bool { return database.Globals.Enabled }) {
+		checkTool(&report, "pg_dumpall")
+	}
+
+	for _, database := range databases {
+		checkDatabase(&report, database, dumpMajor, restoreMajor)
+	}
+
+	probeDatabase := ".doctor"
+	if len(databases) > 0 {
+		probeDatabase = databases[0].Name
+	}
+	checkStorage(&report, provider, probeDatabase)
+	checkTempSpace(&report, provider, databases)
+	checkClock(&report)
+
+	return report
+}
+
+func checkTool(report *DoctorReport, tool string) int {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		report.add(tool, CheckFail, "not found in PATH")
+		return 0
+	}
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		report.add(tool, CheckFail, "run %s --version: %v", tool, err)
+		return 0
+	}
+	match := toolVersionPattern.FindStringSubmatch(string(output))
+	if match == nil {
+		report.add(tool, CheckWarn, "unrecognized version output %q", strings.TrimSpace(string(output)))
+		return 0
+	}
+	major, _ := strconv.Atoi(match[1])
+	report.add(tool, CheckOK, "%s (major %d)", strings.TrimSpace(string(output)), major)
+	return major
+}
+
+func checkDatabase(report *DoctorReport, database config.Database, dumpMajor, restoreMajor int) {
+	name := "database " + database.Name
+	output, err := queryDatabase(database, "SELECT current_setting('server_version_num'), extract(epoch from now())::bigint")
+	if err != nil {
+		report.add(name, CheckFail, "connect: %v", err)
+		return
+	}
+	versionNum, epoch, _ := strings.Cut(output, "|")
+	serverVersion, err := strconv.Atoi(versionNum)
+	if err != nil {
+		report.add(name, CheckWarn, "reachable, but server version %q is not recognized", versionNum)
+		return
+	}
+	serverMajor := serverVersion / 10000
+	report.add(name, CheckOK, "reachable, server major version %d", serverMajor)
+
+	if dumpMajor != 0 && dumpMajor < serverMajor {
+		report.add(name, CheckFail, "pg_dump %d is older than server version %d", dumpMajor, serverMajor)
+	}
+	if restoreMajor != 0 && restoreMajor < serverMajor {
+		report.add(name, CheckFail, "pg_restore %d is older than server version %d", restoreMajor, serverMajor)
+	}
+
+	if seconds, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+		skew := time.Since(time.Unix(seconds, 0))
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > maxClockSkew {
+			report.add(name, CheckWarn, "clock differs from the database server by %s", skew.Round(time.Second))
+		}
+	}
+}
+
+func checkStorage(report *DoctorReport, provider storage.Provider, database string) {
+	name := "storage"
+	// A spool would accept the probe while the remote storage is down.
+	provider = storage.WithoutSpool(provider)
+	if err := provider.EnsureDatabase(database); err != nil {
+		report.add(name, CheckFail, "ensure %s: %v", database, err)
+		return
+	}
+
+	content := []byte("doctor probe " + time.Now().Format(time.RFC3339Nano))
+	probeFile, err := os.CreateTemp("", "doctor-probe-*.tmp")
+	if err != nil {
+		report.add(name, CheckFail, "create probe file: %v", err)
+		return
+	}
+	probePath := probeFile.Name()
+	defer os.Remove(probePath)
+	_, err = probeFile.Write(content)
+	probeFile.Close()
+	if err != nil {
+		report.add(name, CheckFail, "write probe file: %v", err)
+		return
+	}
+
+	filename := fmt.Sprintf("doctor-probe-%d.tmp", time.Now().UnixNano())
+	if err := provider.Save(database, filename, probePath); err != nil {
+		report.add(name, CheckFail, "save probe object: %v", err)
+		return
+	}
+	defer func() {
+		if err := provider.Delete(database, filename); err != nil {
+			report.add(name, CheckFail, "delete probe object: %v", err)
+		}
+	}()
+
+	localPath, cleanup, err := provider.Fetch(database, filename)
+	if err != nil {
+		report.add(name, CheckFail, "fetch probe object: %v", err)
+		return
+	}
+	fetched, err := os.ReadFile(localPath)
+	if cleanup != nil {
+		_ = cleanup()
+	}
+	if err != nil || !bytes.Equal(fetched, content) {
+		report.add(name, CheckFail, "probe object round-trip returned different content")
+		return
+	}
+	report.add(name, CheckOK, "probe object saved, fetched and deleted")
+}
+
+func checkTempSpace(report *DoctorReport, provider storage.Provider, databases []config.Database) {
+	name := "temp directory"
+	tempDir := os.TempDir()
+	free, err := freeSpace(tempDir)
+	if err != nil {
+		report.add(name, CheckWarn, "check free space in %s: %v", tempDir, err)
+		return
+	}
+
+	// Cluster mode databases are dumped one logical database at a time.
+	var lastDumpSize int64
+	for _, database := range databases {
+		sources, err := storedSources(provider, database)
+		if err != nil {
+			continue
+		}
+		for _, source := range sources {
+			files, err := provider.List(source.key)
+			if err != nil {
+				continue
+			}
+			var latest storage.FileInfo
+			for _, file := range files {
+				if strings.HasPrefix(file.Name, "file_") && !storage.IsManifest(file.Name) && file.Modified.After(latest.Modified) {
+					latest = file
+				}
+			}
+			lastDumpSize = max(lastDumpSize, latest.Size)
+		}
+	}
+
+	switch {
+	case lastDumpSize == 0:
+		report.add(name, CheckOK, "%s free in %s, no previous dumps to compare", formatBytes(free), tempDir)
+	case free < uint64(lastDumpSize):
+		report.add(name, CheckFail, "%s free in %s, last dump needed %s", formatBytes(free), tempDir, formatBytes(uint64(lastDumpSize)))
+	case free < 2*uint64(lastDumpSize):
+		report.add(name, CheckWarn, "%s free in %s, less than twice the last dump size %s", formatBytes(free), tempDir, formatBytes(uint64(lastDumpSize)))
+	default:
+		report.add(name, CheckOK, "%s free in %s, last dump size %s", formatBytes(free), tempDir, formatBytes(uint64(lastDumpSize)))
+	}
+}
+
+func checkClock(report *DoctorReport) {
+	name := "clock"
+	if tz := os.Getenv("TZ"); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			report.add(name, CheckFail, "TZ=%s cannot be loaded: %v", tz, err)
+			return
+		}
+	}
+	now := time.Now()
+	if now.Year() < 2024 {
+		report.add(name, CheckFail, "system time %s is in the past", now.Format(time.RFC3339))
+		return
+	}
+	zone, _ := now.Zone()
+	report.add(name, CheckOK, "%s (%s)", now.Format(time.RFC3339), zone)
+}
+
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -1,64 +1,115 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"time"
 
+	"docker-postgres-backuper/config"
 	"docker-postgres-backuper/storage"
 )
 
-func Dump(provider storage.Provider, database, backupType string, databaseList []string) {
-	filename := "file_" + backupType + "_" + time.Now().Format(time.RFC3339) + ".dump"
-
-	list := []string{database}
-	if database == "--all" {
-		list = databaseList
-	}
+// Dump backs up databases as one run. All dumps of a run share the filename
+// and the run ID recorded in their manifests.
+func Dump(provider storage.Provider, databases []config.Database, backupType string) {
+	now := time.Now()
+	filename := "file_" + backupType + "_" + now.Format(time.RFC3339) + ".dump"
+	runID := storage.NewRunID(now)
+	log.Println("backup run", runID, "started")
 
-	for _, item := range list {
-		password, err := getDatabasePassword(item)
-		if err != nil {
-			fmt.Println("resolve database password error:", err)
-			continue
+	for _, item := range databases {
+		if item.Globals.Enabled {
+			dumpGlobals(provider, item, filename)
+			if item.Cluster.Enabled {
+				if err := storage.Cleanup(provider, item.Name, time.Now(), item.Retention.Policy()); err != nil {
+					log.Println("cleanup error:", err)
+				}
+			}
 		}
 
-		tempFile, err := os.CreateTemp("", "pgdump-*.dump")
+		sources, err := backupSources(item)
 		if err != nil {
-			fmt.Println("create temporary file error:", err)
-			continue
-		}
-		tempFilePath := tempFile.Name()
-		tempFile.Close()
-
-		dumpCommand := exec.Command(
-			"pg_dump",
-			"-c",
-			"-Fc",
-			"-U", getDatabaseEnv(item, "POSTGRES_USER"),
-			"-h", getDatabaseEnv(item, "POSTGRES_HOST"),
-			"-f", tempFilePath,
-		)
-		dumpCommand.Env = append(dumpCommand.Env, "PGPASSWORD="+password)
-		dumpCommand.Env = append(dumpCommand.Env, "PGDATABASE="+getDatabaseEnv(item, "POSTGRES_DB"))
-		if message, err := dumpCommand.CombinedOutput(); err != nil {
-			fmt.Println("create backup error:", err, string(message))
-			_ = os.Remove(tempFilePath)
+			fmt.Println("resolve databases to dump error:", err)
 			continue
 		}
 
-		if err := provider.Save(item, filename, tempFilePath); err != nil {
-			fmt.Println("save backup error:", err)
-		} else {
-			_ = os.Remove(tempFilePath)
+		for _, source := range sources {
+			if err := dumpSource(provider, source, backupType, filename, runID); err != nil {
+				fmt.Println(err)
+			}
+
+			if err := storage.Cleanup(provider, source.key, time.Now(), item.Retention.Policy()); err != nil {
+				log.Println("cleanup error:", err)
+			}
 		}
+	}
+}
+
+func dumpSource(provider storage.Provider, source backupSource, backupType, filename, runID string) error {
+	tempFile, err := os.CreateTemp("", "pgdump-*.dump")
+	if err != nil {
+		return fmt.Errorf("create temporary file error: %w", err)
+	}
+	tempFilePath := tempFile.Name()
+	tempFile.Close()
 
-		if err := storage.Cleanup(provider, item, time.Now()); err != nil {
-			log.Println("cleanup error:", err)
+	filters := source.database.Filters
+	schemaOnly := filters.SchemaOnly || backupType == "schema"
+
+	args := []string{"-c", "-Fc", "-d", source.database.DBName(), "-f", tempFilePath}
+	if schemaOnly {
+		args = append(args, "--schema-only")
+	}
+	args = append(args, filters.Args()...)
+	args = append(args, source.database.DumpArgs...)
+
+	dumpCommand := exec.Command("pg_dump", args...)
+	dumpCommand.Env = source.database.ConnectionEnv()
+	if message, err := dumpCommand.CombinedOutput(); err != nil {
+		_ = os.Remove(tempFilePath)
+		return fmt.Errorf("create backup error: %w %s", err, string(message))
+	}
+
+	size, checksum, err := storage.Checksum(tempFilePath)
+	if err != nil {
+		_ = os.Remove(tempFilePath)
+		return fmt.Errorf("checksum backup error: %w", err)
+	}
+	manifest := storage.Manifest{
+		Database:   source.key,
+		Filename:   filename,
+		Type:       backupType,
+		RunID:      runID,
+		Created:    time.Now().UTC(),
+		Size:       size,
+		SHA256:     checksum,
+		SchemaOnly: schemaOnly,
+		Filters:    filters.Args(),
+		DumpArgs:   source.database.DumpArgs,
+	}
+
+	if err := provider.Save(source.key, filename, tempFilePath); err != nil {
+		var partial *storage.PartialSaveError
+		if !errors.As(err, &partial) {
+			return fmt.Errorf("save backup error: %w", err)
+		}
+		// The targets that stored the dump still get its manifest, so they
+		// hold a complete backup with run ID and checksum.
+		_ = os.Remove(tempFilePath)
+		if manifestErr := storage.SaveManifest(partial.Saved, source.key, manifest); manifestErr != nil {
+			err = errors.Join(err, fmt.Errorf("save backup manifest error: %w", manifestErr))
 		}
+		return fmt.Errorf("save backup error: %w", err)
+	}
+	_ = os.Remove(tempFilePath)
+
+	if err := storage.SaveManifest(provider, source.key, manifest); err != nil {
+		return fmt.Errorf("save backup manifest error: %w", err)
 	}
+	return nil
 }
 
 func GetBackupType() string {
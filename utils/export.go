@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"docker-postgres-backuper/archive"
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// Export writes the rows of table from filename, a backup name or selector,
+// to w in format. The data section is extracted from the archive directly,
+// so no database is needed. It returns the number of rows written.
+func Export(provider storage.Provider, database config.Database, filename, table, format string, w io.Writer) (int, error) {
+	source, name := restoreSource(database, filename)
+	resolved, err := storage.ResolveBackup(provider, source.key, name)
+	if err != nil {
+		return 0, fmt.Errorf("resolve backup: %w", err)
+	}
+
+	localPath, cleanup, err := provider.Fetch(source.key, resolved)
+	if err != nil {
+		return 0, fmt.Errorf("fetch backup: %w", err)
+	}
+	defer removeFetched(cleanup)
+
+	toc, err := listArchive(localPath)
+	if err != nil {
+		return 0, err
+	}
+	entries := archive.OfType(archive.Select(toc.Entries, []archive.Table{archive.ParseTable(table)}, nil), "TABLE DATA")
+	if len(entries) == 0 {
+		return 0, errors.New("backup contains no data for table " + table)
+	}
+	listPath, err := writeRestoreList(entries)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(listPath)
+
+	extractCommand := exec.Command("pg_restore", "-a", "-L", listPath, "-f", "-", localPath)
+	var stderr bytes.Buffer
+	extractCommand.Stderr = &stderr
+	stdout, err := extractCommand.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := extractCommand.Start(); err != nil {
+		return 0, fmt.Errorf("start pg_restore: %w", err)
+	}
+
+	rows, convertErr := archive.ConvertCopy(stdout, w, format)
+	if convertErr != nil {
+		// Drain the pipe so pg_restore can exit.
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := extractCommand.Wait(); err != nil {
+		return rows, fmt.Errorf("pg_restore: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return rows, convertErr
+}
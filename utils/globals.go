@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// globalsFilename returns the name of the globals artifact that belongs to
+// the database dump of the same run, e.g. file_daily_<time>.dump becomes
+// globals_daily_<time>.sql.
+func globalsFilename(filename string) string {
+	return "globals_" + strings.TrimSuffix(strings.TrimPrefix(filename, "file_"), ".dump") + ".sql"
+}
+
+// dumpGlobals stores roles and tablespaces of the server as a companion
+// artifact of filename.
+func dumpGlobals(provider storage.Provider, database config.Database, filename string) {
+	tempFile, err := os.CreateTemp("", "pgdumpall-*.sql")
+	if err != nil {
+		fmt.Println("create temporary file error:", err)
+		return
+	}
+	tempFilePath := tempFile.Name()
+	tempFile.Close()
+
+	dumpCommand := exec.Command("pg_dumpall", globalsArgs(database, tempFilePath)...)
+	dumpCommand.Env = database.ConnectionEnv()
+	if message, err := dumpCommand.CombinedOutput(); err != nil {
+		fmt.Println("create globals backup error:", err, string(message))
+		_ = os.Remove(tempFilePath)
+		return
+	}
+
+	if err := provider.Save(database.Name, globalsFilename(filename), tempFilePath); err != nil {
+		fmt.Println("save globals backup error:", err)
+	} else {
+		_ = os.Remove(tempFilePath)
+	}
+}
+
+// globalsArgs returns the pg_dumpall arguments that write the globals of the
+// server of database to path. pg_dumpall only accepts a connection string as
+// its database, so a plain name is left to PGDATABASE from ConnectionEnv.
+func globalsArgs(database config.Database, path string) []string {
+	args := []string{"--globals-only"}
+	if database.URL != "" {
+		args = append(args, "--dbname="+database.URL)
+	}
+	args = append(args, "-f", path)
+	if database.Globals.NoRolePasswords {
+		args = append(args, "--no-role-passwords")
+	}
+	return args
+}
+
+// applyGlobals runs the globals artifact of filename stored under key against
+// the server of database. Statements for roles that already exist fail
+// without stopping the rest, so the errors are reported as warnings.
+func applyGlobals(provider storage.Provider, key string, database config.Database, filename string) error {
+	localPath, cleanup, err := provider.Fetch(key, globalsFilename(filename))
+	if err != nil {
+		return fmt.Errorf("fetch globals: %w", err)
+	}
+	defer removeFetched(cleanup)
+
+	output, err := runSQLFile(database, localPath)
+	if err != nil {
+		return fmt.Errorf("apply globals: %w", err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "ERROR:") {
+			fmt.Println("apply globals warning:", strings.TrimSpace(line))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+
+	"docker-postgres-backuper/config"
+)
+
+func TestGlobalsArgs(t *testing.T) {
+	database := config.Database{Name: "users", Database: "postgres"}
+	if args := globalsArgs(database, "/tmp/globals.sql"); !slices.Equal(args, []string{"--globals-only", "-f", "/tmp/globals.sql"}) {
+		t.Fatalf("unexpected arguments without url: %v", args)
+	}
+
+	database.URL = "postgres://app:secret@db:5432/app"
+	database.Globals.NoRolePasswords = true
+	expected := []string{"--globals-only", "--dbname=postgres://app:secret@db:5432/app", "-f", "/tmp/globals.sql", "--no-role-passwords"}
+	if args := globalsArgs(database, "/tmp/globals.sql"); !slices.Equal(args, expected) {
+		t.Fatalf("unexpected arguments with url: %v", args)
+	}
+}
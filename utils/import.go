@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"docker-postgres-backuper/archive"
+	"docker-postgres-backuper/storage"
+)
+
+// importTypes are the backup classes an external dump can be filed under.
+var importTypes = []string{"daily", "weekly", "monthly", "manual", "schema"}
+
+// ImportOptions controls how an external dump is filed.
+type ImportOptions struct {
+	Type string
+	// Timestamp defaults to the creation time recorded in the archive.
+	Timestamp time.Time
+}
+
+// Import validates the custom format dump at path and stores it under the
+// standard naming, with a manifest, so that it is listed, restored and
+// expired like the controller's own backups. It returns the stored name.
+func Import(provider storage.Provider, database, path string, options ImportOptions) (string, error) {
+	if !slices.Contains(importTypes, options.Type) {
+		return "", fmt.Errorf("unsupported backup type %q", options.Type)
+	}
+
+	toc, err := listArchive(path)
+	if err != nil {
+		return "", fmt.Errorf("validate dump: %w", err)
+	}
+	created := options.Timestamp
+	if created.IsZero() {
+		created, err = archiveCreated(toc.Header, path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	filename := "file_" + options.Type + "_" + created.Format(time.RFC3339) + ".dump"
+	if err := provider.EnsureDatabase(database); err != nil {
+		return "", err
+	}
+	files, err := provider.List(database)
+	if err != nil {
+		return "", fmt.Errorf("list backups: %w", err)
+	}
+	for _, file := range files {
+		if file.Name == filename {
+			return "", fmt.Errorf("backup %s already exists", filename)
+		}
+	}
+
+	// Providers may move the file they save, so the original stays untouched.
+	tempFilePath, err := copyToTemp(path)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tempFilePath)
+
+	size, checksum, err := storage.Checksum(tempFilePath)
+	if err != nil {
+		return "", fmt.Errorf("checksum dump: %w", err)
+	}
+	manifest := storage.Manifest{
+		Database:     database,
+		Filename:     filename,
+		Type:         options.Type,
+		Created:      created.UTC(),
+		Size:         size,
+		SHA256:       checksum,
+		SchemaOnly:   options.Type == "schema",
+		ImportedFrom: filepath.Base(path),
+	}
+
+	if err := provider.Save(database, filename, tempFilePath); err != nil {
+		return "", fmt.Errorf("save backup: %w", err)
+	}
+	if err := storage.SaveManifest(provider, database, manifest); err != nil {
+		return "", fmt.Errorf("save backup manifest: %w", err)
+	}
+	return filename, nil
+}
+
+// archiveCreated parses the creation time from the archive header, such as
+// "2025-07-04 09:00:00 CEST". Time zone abbreviations are ambiguous and Go
+// only knows those of UTC and the local zone, so for any other zone the
+// modification time of the file at path is used instead.
+func archiveCreated(header archive.Header, path string) (time.Time, error) {
+	const layout = "2006-01-02 15:04:05"
+	fields := strings.Fields(header.Created)
+	stamp, zone := "", ""
+	if len(fields) >= 2 {
+		stamp, zone = fields[0]+" "+fields[1], strings.Join(fields[2:], " ")
+	}
+
+	switch zone {
+	case "UTC", "GMT", "Z":
+		if created, err := time.Parse(layout, stamp); err == nil {
+			return created, nil
+		}
+	default:
+		if location, err := time.LoadLocation(zone); err == nil && zone != "" && zone != "Local" {
+			if created, err := time.ParseInLocation(layout, stamp, location); err == nil {
+				return created, nil
+			}
+		}
+		// Parse uses the local zone for abbreviations it defines and a
+		// fabricated zone with a zero offset for all others.
+		if created, err := time.ParseInLocation(layout+" MST", header.Created, time.Local); err == nil && created.Location() == time.Local {
+			return created, nil
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("stat dump: %w", err)
+	}
+	log.Printf("archive creation time %q has an unknown time zone, using the file modification time; pass --timestamp to override", header.Created)
+	return info.ModTime(), nil
+}
+
+func copyToTemp(path string) (string, error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open dump: %w", err)
+	}
+	defer source.Close()
+
+	tempFile, err := os.CreateTemp("", "import-*.dump")
+	if err != nil {
+		return "", fmt.Errorf("create temporary file: %w", err)
+	}
+	if _, err := io.Copy(tempFile, source); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("copy dump: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("copy dump: %w", err)
+	}
+	return tempFile.Name(), nil
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"docker-postgres-backuper/archive"
+)
+
+func TestArchiveCreated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "external.dump")
+	if err := os.WriteFile(path, []byte("dump"), 0o600); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+	modified := time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatalf("set times: %v", err)
+	}
+
+	cases := []struct {
+		created string
+		want    time.Time
+	}{
+		{"2025-07-04 09:00:00 UTC", time.Date(2025, 7, 4, 9, 0, 0, 0, time.UTC)},
+		{"2025-07-04 09:00:00 Europe/Berlin", time.Date(2025, 7, 4, 7, 0, 0, 0, time.UTC)},
+		// Unknown abbreviations would otherwise be read as UTC.
+		{"2025-07-04 09:00:00 XYZT", modified},
+		{"", modified},
+	}
+	for _, tc := range cases {
+		got, err := archiveCreated(archive.Header{Created: tc.created}, path)
+		if err != nil {
+			t.Fatalf("archiveCreated(%q) returned error: %v", tc.created, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("archiveCreated(%q) = %s, want %s", tc.created, got, tc.want)
+		}
+	}
+}
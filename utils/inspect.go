@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+
+	"docker-postgres-backuper/archive"
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// Inspection describes the contents of a stored backup.
+type Inspection struct {
+	Database string `json:"database"`
+	Filename string `json:"filename"`
+	archive.Summary
+}
+
+// Inspect summarizes filename, a backup name or selector, without restoring
+// it.
+func Inspect(provider storage.Provider, database config.Database, filename string) (Inspection, error) {
+	source, name := restoreSource(database, filename)
+	resolved, err := storage.ResolveBackup(provider, source.key, name)
+	if err != nil {
+		return Inspection{}, fmt.Errorf("resolve backup: %w", err)
+	}
+
+	localPath, cleanup, err := provider.Fetch(source.key, resolved)
+	if err != nil {
+		return Inspection{}, fmt.Errorf("fetch backup: %w", err)
+	}
+	defer removeFetched(cleanup)
+
+	toc, err := listArchive(localPath)
+	if err != nil {
+		return Inspection{}, err
+	}
+	return Inspection{Database: source.key, Filename: resolved, Summary: archive.Summarize(toc)}, nil
+}
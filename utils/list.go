@@ -3,22 +3,96 @@ package utils
 import (
 	"log"
 	"sort"
+	"time"
 
 	"docker-postgres-backuper/storage"
 )
 
+// listedBackup is a backup as shown by List.
+type listedBackup struct {
+	name     string
+	key      string
+	filename string
+	manifest bool
+}
+
+// List prints the backups of a database. Backups of logical databases dumped
+// in cluster mode are printed as "<dbname>/<file>". Pinned backups are
+// followed by the owner and reason of the pin.
 func List(provider storage.Provider, database string) {
-	files, err := provider.List(database)
+	backups, err := listBackups(provider, database)
 	if err != nil {
 		log.Println("list backups error:", err)
 		return
 	}
 
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].Name < files[j].Name
-	})
+	for _, backup := range backups {
+		if backup.manifest {
+			manifest, err := storage.LoadManifest(provider, backup.key, backup.filename)
+			if err == nil && manifest.Pin != nil {
+				log.Printf("%s [pinned by %s: %s]", backup.name, manifest.Pin.Owner, manifest.Pin.Reason)
+				continue
+			}
+		}
+		log.Println(backup.name)
+	}
+}
+
+func listBackups(provider storage.Provider, database string) ([]listedBackup, error) {
+	backups, err := listKey(provider, database, "")
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := provider.Children(database)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		nested, err := listKey(provider, database+"/"+child, child+"/")
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, nested...)
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].name < backups[j].name })
+	return backups, nil
+}
 
+func listKey(provider storage.Provider, key, prefix string) ([]listedBackup, error) {
+	files, err := provider.List(key)
+	if err != nil {
+		return nil, err
+	}
+	manifests := map[string]bool{}
 	for _, file := range files {
-		log.Println(file.Name)
+		manifests[file.Name] = true
+	}
+	var backups []listedBackup
+	for _, file := range files {
+		if !storage.IsManifest(file.Name) {
+			backups = append(backups, listedBackup{
+				name:     prefix + file.Name,
+				key:      key,
+				filename: file.Name,
+				manifest: manifests[storage.ManifestName(file.Name)],
+			})
+		}
+	}
+	return backups, nil
+}
+
+// ListPending prints the backups that wait in a spool for their upload.
+func ListPending(provider storage.Provider) {
+	for _, spool := range storage.Spools(provider) {
+		pending, err := spool.Pending()
+		if err != nil {
+			log.Println("list pending uploads error:", err)
+			continue
+		}
+		for _, file := range pending {
+			log.Printf("%s: %s/%s (%d bytes, spooled %s)", spool.Name, file.Database, file.Filename, file.Size, file.Spooled.Format(time.RFC3339))
+		}
 	}
 }
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"slices"
+	"time"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// Pin exempts a backup, and the globals dumped in the same run, from
+// retention. The pin is recorded in the manifest of each artifact, which is
+// created from the listing for artifacts that have none.
+func Pin(provider storage.Provider, database config.Database, filename, reason, owner string) error {
+	if reason == "" || owner == "" {
+		return errors.New("pin needs a reason and an owner")
+	}
+	source, name, err := pinTarget(database, filename)
+	if err != nil {
+		return err
+	}
+	pin := &storage.Pin{Reason: reason, Owner: owner, Pinned: time.Now().UTC()}
+
+	manifest, err := pinManifest(provider, source.key, name)
+	if err != nil {
+		return err
+	}
+	if err := savePin(provider, source.key, manifest, pin); err != nil {
+		return err
+	}
+
+	// Globals are stored under the service, next to its own dumps.
+	globals, err := pinManifest(provider, database.Name, globalsFilename(name))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("pin globals: %w", err)
+	}
+	if err := savePin(provider, database.Name, globals, pin); err != nil {
+		return fmt.Errorf("pin globals: %w", err)
+	}
+	return nil
+}
+
+// Unpin makes a pinned backup and its globals subject to retention again.
+func Unpin(provider storage.Provider, database config.Database, filename string) error {
+	source, name, err := pinTarget(database, filename)
+	if err != nil {
+		return err
+	}
+	manifest, err := pinManifest(provider, source.key, name)
+	if err != nil {
+		return err
+	}
+	if manifest.Pin == nil {
+		return fmt.Errorf("backup %s is not pinned", filename)
+	}
+	if err := savePin(provider, source.key, manifest, nil); err != nil {
+		return err
+	}
+
+	globals, err := pinManifest(provider, database.Name, globalsFilename(name))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("unpin globals: %w", err)
+	}
+	if globals.Pin == nil {
+		return nil
+	}
+	if err := savePin(provider, database.Name, globals, nil); err != nil {
+		return fmt.Errorf("unpin globals: %w", err)
+	}
+	return nil
+}
+
+// pinTarget resolves an exact backup name; selectors are refused.
+func pinTarget(database config.Database, filename string) (backupSource, string, error) {
+	source, name := restoreSource(database, filename)
+	if storage.IsSelector(name) {
+		return source, name, fmt.Errorf("%s is a selector, pin needs the exact backup name", name)
+	}
+	return source, name, nil
+}
+
+// pinManifest loads the manifest of filename stored under key, or builds one
+// from the listing when the artifact has none.
+func pinManifest(provider storage.Provider, key, filename string) (storage.Manifest, error) {
+	files, err := provider.List(key)
+	if err != nil {
+		return storage.Manifest{}, fmt.Errorf("list backups: %w", err)
+	}
+	index := slices.IndexFunc(files, func(file storage.FileInfo) bool { return file.Name == filename })
+	if index < 0 {
+		return storage.Manifest{}, fmt.Errorf("backup %s not found: %w", filename, fs.ErrNotExist)
+	}
+
+	if slices.ContainsFunc(files, func(file storage.FileInfo) bool { return file.Name == storage.ManifestName(filename) }) {
+		manifest, err := storage.LoadManifest(provider, key, filename)
+		if err != nil {
+			return storage.Manifest{}, fmt.Errorf("load manifest: %w", err)
+		}
+		return manifest, nil
+	}
+
+	manifest := storage.Manifest{Database: key, Filename: filename, Created: files[index].Modified, Size: files[index].Size}
+	if backupType, created, ok := storage.ParseBackupName(filename); ok {
+		manifest.Type = backupType
+		manifest.Created = created
+	}
+	return manifest, nil
+}
+
+// savePin stores manifest with pin, or without one when pin is nil, on the
+// targets that hold the artifact.
+func savePin(provider storage.Provider, key string, manifest storage.Manifest, pin *storage.Pin) error {
+	provider, err := storage.Holding(provider, key, manifest.Filename)
+	if err != nil {
+		return fmt.Errorf("find targets: %w", err)
+	}
+	manifest.Pin = pin
+	if err := storage.SaveManifest(provider, key, manifest); err != nil {
+		return fmt.Errorf("save manifest: %w", err)
+	}
+	setPinnedTag(provider, key, manifest.Filename, map[string]string{storage.PinnedTag: fmt.Sprint(pin != nil)})
+	return nil
+}
+
+// setPinnedTag mirrors the pin as object tags where the provider supports
+// them. The manifest stays authoritative, so failures are only logged.
+func setPinnedTag(provider storage.Provider, key, filename string, tags map[string]string) {
+	tagger, ok := provider.(storage.Tagger)
+	if !ok {
+		return
+	}
+	if err := tagger.SetTags(key, filename, tags); err != nil {
+		log.Println("tag backup error:", err)
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"docker-postgres-backuper/config"
+)
+
+// queryDatabase runs a single SQL statement through psql and returns the
+// unaligned, tuples-only output.
+func queryDatabase(database config.Database, sql string) (string, error) {
+	queryCommand := exec.Command(
+		"psql",
+		"-X",
+		"-A",
+		"-t",
+		"-v", "ON_ERROR_STOP=1",
+		"-d", database.DBName(),
+		"-c", sql,
+	)
+	queryCommand.Env = database.ConnectionEnv()
+	output, err := queryCommand.CombinedOutput()
+	if err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// runSQLFile executes a SQL script through psql without stopping on errors
+// and returns the combined output.
+func runSQLFile(database config.Database, path string) (string, error) {
+	scriptCommand := exec.Command(
+		"psql",
+		"-X",
+		"-q",
+		"-d", database.DBName(),
+		"-f", path,
+	)
+	scriptCommand.Env = database.ConnectionEnv()
+	output, err := scriptCommand.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// quoteIdentifier quotes name for use as an SQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes value for use as an SQL string literal.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// executeSQLFile runs a SQL script in a single transaction and stops at the
+// first error.
+func executeSQLFile(database config.Database, path string) error {
+	scriptCommand := exec.Command(
+		"psql",
+		"-X",
+		"-q",
+		"-1",
+		"-v", "ON_ERROR_STOP=1",
+		"-d", database.DBName(),
+		"-f", path,
+	)
+	scriptCommand.Env = database.ConnectionEnv()
+	if output, err := scriptCommand.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
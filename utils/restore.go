@@ -1,48 +1,265 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
+	"sort"
+	"strings"
 
+	"docker-postgres-backuper/config"
 	"docker-postgres-backuper/storage"
 )
 
-func Restore(provider storage.Provider, database, filename string, databaseList []string) {
-	list := []string{database}
-	if database == "--all" {
-		list = databaseList
+// RestoreOptions tunes how a backup is restored.
+type RestoreOptions struct {
+	// WithGlobals applies the roles and tablespaces dumped in the same run
+	// before the database itself is restored.
+	WithGlobals bool
+	// CreateDatabase creates the target database of a clone when missing.
+	CreateDatabase bool
+	// SkipSafetySnapshot disables the pre-restore dump of the target.
+	SkipSafetySnapshot bool
+	// TerminateSessions blocks new connections and terminates existing
+	// sessions of the target for the duration of the restore.
+	TerminateSessions bool
+	// SingleTransaction and ExitOnError are passed on to pg_restore.
+	SingleTransaction bool
+	ExitOnError       bool
+	// Analyze and VacuumAnalyze refresh planner statistics afterwards.
+	Analyze       bool
+	VacuumAnalyze bool
+	// Tables ("schema.table") and Schemas restrict the restore to the
+	// matching archive entries.
+	Tables  []string
+	Schemas []string
+	// DataOnly restores only table data into the existing tables.
+	DataOnly bool
+	// IntoSchema and ScratchDatabase restore the selected tables next to
+	// the live ones, into a side schema or another database on the server.
+	IntoSchema      string
+	ScratchDatabase string
+}
+
+// RestoreResult is the outcome of restoring a single database. Rollback is
+// the command that restores the safety snapshot, when one was taken.
+type RestoreResult struct {
+	Database string
+	Filename string
+	Rollback string
+	Err      error
+}
+
+// Restore restores filename, a backup name or selector, into every database.
+// Cluster mode databases restore each logical database found in storage
+// unless filename names one of them.
+func Restore(provider storage.Provider, databases []config.Database, filename string, options RestoreOptions) []RestoreResult {
+	var results []RestoreResult
+	for _, item := range databases {
+		source, name := restoreSource(item, filename)
+		sources := []backupSource{source}
+		if item.Cluster.Enabled && source.key == item.Name {
+			var err error
+			sources, err = storedSources(provider, item)
+			if err != nil {
+				results = append(results, RestoreResult{Database: item.Name, Err: err})
+				continue
+			}
+		}
+
+		globalsApplied := false
+		for _, source := range sources {
+			result := RestoreResult{Database: source.key, Filename: name}
+
+			resolved, err := storage.ResolveBackup(provider, source.key, name)
+			if err != nil {
+				result.Err = fmt.Errorf("resolve backup: %w", err)
+				results = append(results, result)
+				continue
+			}
+			result.Filename = resolved
+			if resolved != name {
+				fmt.Printf("%s: %s resolved to %s\n", source.key, name, resolved)
+			}
+
+			if options.isolated() {
+				result.Err = restoreIsolated(provider, source.key, source.database, resolved, options)
+				results = append(results, result)
+				continue
+			}
+
+			if !options.SkipSafetySnapshot {
+				snapshot, err := safetySnapshot(provider, source.key, source.database, item.Retention.Policy())
+				if err != nil {
+					result.Err = err
+					results = append(results, result)
+					continue
+				}
+				result.Rollback = "./controller restore " + item.Name + " " + listedName(item.Name, source.key, snapshot)
+			}
+
+			if options.WithGlobals && !globalsApplied {
+				if err := applyGlobals(provider, item.Name, item, resolved); err != nil {
+					result.Err = err
+					results = append(results, result)
+					continue
+				}
+				globalsApplied = true
+			}
+
+			result.Err = restoreInto(provider, source.key, source.database, resolved, options)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// RestoreRun restores every database from the dumps of one backup run. With
+// runID "latest" the newest run that contains all of the databases is used.
+func RestoreRun(provider storage.Provider, databases []config.Database, runID string, options RestoreOptions) []RestoreResult {
+	type plannedSource struct {
+		item   config.Database
+		source backupSource
+		runs   map[string]string
 	}
 
-	for _, item := range list {
-		password, err := getDatabasePassword(item)
+	var (
+		planned []plannedSource
+		results []RestoreResult
+	)
+	for _, item := range databases {
+		sources, err := storedSources(provider, item)
 		if err != nil {
-			fmt.Println("resolve database password error:", err)
+			results = append(results, RestoreResult{Database: item.Name, Err: err})
 			continue
 		}
+		for _, source := range sources {
+			runs, err := backupRuns(provider, source.key)
+			if err != nil {
+				results = append(results, RestoreResult{Database: source.key, Err: err})
+				continue
+			}
+			planned = append(planned, plannedSource{item: item, source: source, runs: runs})
+		}
+	}
+	if len(results) > 0 {
+		return results
+	}
 
-		localPath, cleanup, err := provider.Fetch(item, filename)
+	if runID == "latest" {
+		runSets := make([]map[string]string, 0, len(planned))
+		for _, p := range planned {
+			runSets = append(runSets, p.runs)
+		}
+		latest, err := latestCommonRun(runSets)
 		if err != nil {
-			fmt.Println("fetch backup error:", err)
+			return []RestoreResult{{Err: err}}
+		}
+		runID = latest
+		fmt.Println("restoring backup run", runID)
+	}
+
+	globalsApplied := map[string]error{}
+	for _, p := range planned {
+		result := RestoreResult{Database: p.source.key}
+		filename, ok := p.runs[runID]
+		if !ok {
+			result.Err = fmt.Errorf("no backup from run %s", runID)
+			results = append(results, result)
 			continue
 		}
+		result.Filename = filename
+
+		if !options.SkipSafetySnapshot {
+			snapshot, err := safetySnapshot(provider, p.source.key, p.source.database, p.item.Retention.Policy())
+			if err != nil {
+				result.Err = err
+				results = append(results, result)
+				continue
+			}
+			result.Rollback = "./controller restore " + p.item.Name + " " + listedName(p.item.Name, p.source.key, snapshot)
+		}
+
+		if options.WithGlobals {
+			err, done := globalsApplied[p.item.Name]
+			if !done {
+				err = applyGlobals(provider, p.item.Name, p.item, filename)
+				globalsApplied[p.item.Name] = err
+			}
+			if err != nil {
+				result.Err = err
+				results = append(results, result)
+				continue
+			}
+		}
+
+		result.Err = restoreInto(provider, p.source.key, p.source.database, filename, options)
+		results = append(results, result)
+	}
+	return results
+}
+
+// restoreBackup runs pg_restore for the archive at localPath against target.
+// Objects are dropped before they are recreated unless clean is false.
+func restoreBackup(target config.Database, localPath string, clean bool, extraArgs ...string) error {
+	args := []string{"-d", target.DBName()}
+	if clean {
+		args = append(args, "-c")
+	}
+	args = append(args, extraArgs...)
+	args = append(args, target.RestoreArgs...)
+	args = append(args, localPath)
+
+	restoreCommand := exec.Command("pg_restore", args...)
+	restoreCommand.Env = target.ConnectionEnv()
+	if message, err := restoreCommand.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_restore: %w: %s", err, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
 
-		dumpCommand := exec.Command(
-			"pg_restore",
-			"-c",
-			"-U", getDatabaseEnv(item, "POSTGRES_USER"),
-			"-h", getDatabaseEnv(item, "POSTGRES_HOST"),
-			"-d", getDatabaseEnv(item, "POSTGRES_DB"),
-			localPath,
-		)
-		dumpCommand.Env = append(dumpCommand.Env, "PGPASSWORD="+password)
-		if message, err := dumpCommand.CombinedOutput(); err != nil {
-			fmt.Println("restore backup error:", err, string(message))
+// backupRuns maps run IDs to the backup of database that belongs to them.
+// Backups without a manifest or run ID are not part of any run.
+func backupRuns(provider storage.Provider, database string) (map[string]string, error) {
+	files, err := provider.List(database)
+	if err != nil {
+		return nil, fmt.Errorf("list backups: %w", err)
+	}
+	runs := map[string]string{}
+	for _, file := range files {
+		if _, _, ok := storage.ParseBackupName(file.Name); !ok {
+			continue
 		}
+		manifest, err := storage.LoadManifest(provider, database, file.Name)
+		if err != nil || manifest.RunID == "" {
+			continue
+		}
+		runs[manifest.RunID] = file.Name
+	}
+	return runs, nil
+}
 
-		if cleanup != nil {
-			if err := cleanup(); err != nil {
-				fmt.Println("cleanup temporary file error:", err)
+// latestCommonRun returns the newest run ID present in every run set.
+func latestCommonRun(runSets []map[string]string) (string, error) {
+	if len(runSets) == 0 {
+		return "", errors.New("no databases to restore")
+	}
+	var candidates []string
+	for runID := range runSets[0] {
+		candidates = append(candidates, runID)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(candidates)))
+	for _, runID := range candidates {
+		common := true
+		for _, runs := range runSets[1:] {
+			if _, ok := runs[runID]; !ok {
+				common = false
+				break
 			}
 		}
+		if common {
+			return runID, nil
+		}
 	}
+	return "", errors.New("no backup run contains all selected databases")
 }
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// restoreInto restores the backup stored under key into target, wrapped in
+// the preparation and follow-up steps selected in options. Every step is
+// logged with its duration.
+func restoreInto(provider storage.Provider, key string, target config.Database, filename string, options RestoreOptions, extraArgs ...string) error {
+	localPath, cleanup, err := provider.Fetch(key, filename)
+	if err != nil {
+		return fmt.Errorf("fetch backup: %w", err)
+	}
+	defer removeFetched(cleanup)
+
+	if options.selective() {
+		entries, err := selectEntries(localPath, options)
+		if err != nil {
+			return err
+		}
+		listPath, err := writeRestoreList(entries)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(listPath)
+		extraArgs = append(extraArgs, "-L", listPath)
+	}
+	if options.DataOnly {
+		extraArgs = append(extraArgs, "--data-only")
+	}
+	if options.SingleTransaction {
+		extraArgs = append(extraArgs, "--single-transaction")
+	}
+	if options.ExitOnError {
+		extraArgs = append(extraArgs, "--exit-on-error")
+	}
+
+	if options.TerminateSessions {
+		var reopen func() error
+		err := restoreStep(target, "block connections and terminate sessions", func() error {
+			var err error
+			reopen, err = blockConnections(target)
+			return err
+		})
+		// blockConnections may fail after revoking CONNECT, so the grant is
+		// restored whenever reopen is set, even if the step failed.
+		defer func() {
+			if reopen == nil {
+				return
+			}
+			if err := restoreStep(target, "allow connections", reopen); err != nil {
+				fmt.Println(err)
+			}
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := restoreStep(target, "pg_restore "+filename, func() error {
+		return restoreBackup(target, localPath, !options.DataOnly, extraArgs...)
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case options.VacuumAnalyze:
+		return restoreStep(target, "VACUUM ANALYZE", func() error {
+			_, err := queryDatabase(target, "VACUUM ANALYZE")
+			return err
+		})
+	case options.Analyze:
+		return restoreStep(target, "ANALYZE", func() error {
+			_, err := queryDatabase(target, "ANALYZE")
+			return err
+		})
+	}
+	return nil
+}
+
+func removeFetched(cleanup func() error) {
+	if cleanup == nil {
+		return
+	}
+	if err := cleanup(); err != nil {
+		log.Println("cleanup temporary file error:", err)
+	}
+}
+
+func restoreStep(target config.Database, name string, step func() error) error {
+	start := time.Now()
+	log.Printf("restore %s: %s", target.Name, name)
+	if err := step(); err != nil {
+		log.Printf("restore %s: %s failed after %s", target.Name, name, time.Since(start).Round(time.Millisecond))
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	log.Printf("restore %s: %s done in %s", target.Name, name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// blockConnections revokes CONNECT from PUBLIC and terminates the other
+// sessions of the target database. Superusers and the owner can still
+// connect, which keeps the restore itself working. The returned function
+// restores the privilege when it was granted before.
+func blockConnections(target config.Database) (func() error, error) {
+	output, err := queryDatabase(target, "SELECT current_database(), has_database_privilege('public', current_database(), 'CONNECT')")
+	if err != nil {
+		return nil, err
+	}
+	name, granted, _ := strings.Cut(output, "|")
+
+	reopen := func() error { return nil }
+	if granted == "t" {
+		if _, err := queryDatabase(target, "REVOKE CONNECT ON DATABASE "+quoteIdentifier(name)+" FROM PUBLIC"); err != nil {
+			return nil, err
+		}
+		reopen = func() error {
+			_, err := queryDatabase(target, "GRANT CONNECT ON DATABASE "+quoteIdentifier(name)+" TO PUBLIC")
+			return err
+		}
+	}
+
+	terminated, err := queryDatabase(target, "SELECT count(pg_terminate_backend(pid)) FROM pg_stat_activity WHERE datname = current_database() AND pid <> pg_backend_pid()")
+	if err != nil {
+		return reopen, err
+	}
+	log.Printf("restore %s: terminated %s sessions", target.Name, terminated)
+	return reopen, nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "testing"
+
+func TestLatestCommonRun(t *testing.T) {
+	runSets := []map[string]string{
+		{"20250704T090000Z": "a", "20250704T150000Z": "b", "20250705T030000Z": "c"},
+		{"20250704T090000Z": "a", "20250704T150000Z": "b"},
+		{"20250704T090000Z": "a", "20250704T150000Z": "b", "20250703T210000Z": "z"},
+	}
+	runID, err := latestCommonRun(runSets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runID != "20250704T150000Z" {
+		t.Fatalf("expected 20250704T150000Z, got %s", runID)
+	}
+
+	if _, err := latestCommonRun([]map[string]string{{"20250704T090000Z": "a"}, {"20250704T150000Z": "b"}}); err == nil {
+		t.Fatal("expected error when no run is shared")
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// safetySnapshot takes a full dump of target under key before a restore
+// overwrites it and returns the snapshot filename. Filters are ignored so the
+// snapshot covers everything the restore may drop.
+func safetySnapshot(provider storage.Provider, key string, target config.Database, policy storage.RetentionPolicy) (string, error) {
+	target.Filters = config.Filters{}
+	filename := "file_" + storage.PreRestoreType + "_" + time.Now().Format(time.RFC3339) + ".dump"
+
+	start := time.Now()
+	if err := dumpSource(provider, backupSource{key: key, database: target}, storage.PreRestoreType, filename, ""); err != nil {
+		return "", fmt.Errorf("safety snapshot: %w", err)
+	}
+	log.Printf("safety snapshot %s/%s saved in %s", key, filename, time.Since(start).Round(time.Millisecond))
+
+	if err := storage.Cleanup(provider, key, time.Now(), policy); err != nil {
+		log.Println("cleanup error:", err)
+	}
+	return filename, nil
+}
+
+// listedName returns filename stored under key as shown by List for service.
+func listedName(service, key, filename string) string {
+	if child, nested := strings.CutPrefix(key, service+"/"); nested {
+		return child + "/" + filename
+	}
+	return filename
+}
@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"docker-postgres-backuper/archive"
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+func (o RestoreOptions) selective() bool {
+	return len(o.Tables) > 0 || len(o.Schemas) > 0
+}
+
+func (o RestoreOptions) isolated() bool {
+	return o.IntoSchema != "" || o.ScratchDatabase != ""
+}
+
+func (o RestoreOptions) tables() []archive.Table {
+	tables := make([]archive.Table, 0, len(o.Tables))
+	for _, table := range o.Tables {
+		tables = append(tables, archive.ParseTable(table))
+	}
+	return tables
+}
+
+// listArchive returns the table of contents of the archive at localPath.
+func listArchive(localPath string) (archive.TOC, error) {
+	listCommand := exec.Command("pg_restore", "-l", localPath)
+	var stderr bytes.Buffer
+	listCommand.Stderr = &stderr
+	output, err := listCommand.Output()
+	if err != nil {
+		return archive.TOC{}, fmt.Errorf("pg_restore -l: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return archive.Parse(bytes.NewReader(output))
+}
+
+// selectEntries returns the archive entries matching the table and schema
+// selection of options.
+func selectEntries(localPath string, options RestoreOptions) ([]archive.Entry, error) {
+	toc, err := listArchive(localPath)
+	if err != nil {
+		return nil, err
+	}
+	entries := archive.Select(toc.Entries, options.tables(), options.Schemas)
+	switch {
+	case options.DataOnly:
+		entries = archive.OfType(entries, "TABLE DATA", "SEQUENCE SET")
+	case options.isolated():
+		// Defaults and foreign keys refer to objects that are not restored
+		// next to the live tables.
+		entries = archive.OfType(entries, "TABLE", "TABLE DATA", "CONSTRAINT")
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no archive entries match the selected tables and schemas")
+	}
+	return entries, nil
+}
+
+func writeRestoreList(entries []archive.Entry) (string, error) {
+	listFile, err := os.CreateTemp("", "pgrestore-*.list")
+	if err != nil {
+		return "", fmt.Errorf("create restore list: %w", err)
+	}
+	if err := archive.WriteList(listFile, entries); err != nil {
+		listFile.Close()
+		os.Remove(listFile.Name())
+		return "", fmt.Errorf("write restore list: %w", err)
+	}
+	if err := listFile.Close(); err != nil {
+		os.Remove(listFile.Name())
+		return "", fmt.Errorf("write restore list: %w", err)
+	}
+	return listFile.Name(), nil
+}
+
+// restoreIsolated restores the selected tables next to the live ones so that
+// rows can be compared and copied back by hand. The live tables are not
+// touched.
+func restoreIsolated(provider storage.Provider, key string, database config.Database, filename string, options RestoreOptions) error {
+	localPath, cleanup, err := provider.Fetch(key, filename)
+	if err != nil {
+		return fmt.Errorf("fetch backup: %w", err)
+	}
+	defer removeFetched(cleanup)
+
+	entries, err := selectEntries(localPath, options)
+	if err != nil {
+		return err
+	}
+	listPath, err := writeRestoreList(entries)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(listPath)
+
+	if options.ScratchDatabase != "" {
+		target := database.ForDatabase(options.ScratchDatabase)
+		target.Name = key + "/" + options.ScratchDatabase
+		if err := createDatabase(target); err != nil {
+			return err
+		}
+		if err := createSchemas(target, entrySchemas(entries)); err != nil {
+			return err
+		}
+		return restoreStep(target, "pg_restore "+filename, func() error {
+			return restoreBackup(target, localPath, false, "-L", listPath)
+		})
+	}
+
+	var tables []archive.Table
+	for _, entry := range entries {
+		if entry.Type == "TABLE" {
+			tables = append(tables, archive.Table{Schema: entry.Schema, Name: entry.Name})
+		}
+	}
+	scriptPath, err := renderScript(localPath, listPath, tables, options.IntoSchema)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(scriptPath)
+
+	if err := createSchemas(database, []string{options.IntoSchema}); err != nil {
+		return err
+	}
+	return restoreStep(database, "restore "+filename+" into schema "+options.IntoSchema, func() error {
+		return executeSQLFile(database, scriptPath)
+	})
+}
+
+// renderScript converts the listed entries to SQL with the tables moved to
+// schema.
+func renderScript(localPath, listPath string, tables []archive.Table, schema string) (string, error) {
+	renderCommand := exec.Command("pg_restore", "-L", listPath, "-f", "-", localPath)
+	var stderr bytes.Buffer
+	renderCommand.Stderr = &stderr
+	output, err := renderCommand.Output()
+	if err != nil {
+		return "", fmt.Errorf("pg_restore -f: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	scriptFile, err := os.CreateTemp("", "pgrestore-*.sql")
+	if err != nil {
+		return "", fmt.Errorf("create restore script: %w", err)
+	}
+	_, err = scriptFile.WriteString(archive.RenameSchema(string(output), tables, schema))
+	if closeErr := scriptFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(scriptFile.Name())
+		return "", fmt.Errorf("write restore script: %w", err)
+	}
+	return scriptFile.Name(), nil
+}
+
+func entrySchemas(entries []archive.Entry) []string {
+	var schemas []string
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Schema != "" && !seen[entry.Schema] {
+			seen[entry.Schema] = true
+			schemas = append(schemas, entry.Schema)
+		}
+	}
+	return schemas
+}
+
+func createSchemas(database config.Database, schemas []string) error {
+	for _, schema := range schemas {
+		if _, err := queryDatabase(database, "CREATE SCHEMA IF NOT EXISTS "+quoteIdentifier(schema)); err != nil {
+			return fmt.Errorf("create schema %s: %w", schema, err)
+		}
+	}
+	return nil
+}
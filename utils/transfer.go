@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+// Download copies filename, a backup name or selector, to w and returns the
+// resolved name.
+func Download(provider storage.Provider, database config.Database, filename string, w io.Writer) (string, error) {
+	source, name := restoreSource(database, filename)
+	resolved, err := storage.ResolveBackup(provider, source.key, name)
+	if err != nil {
+		return "", fmt.Errorf("resolve backup: %w", err)
+	}
+
+	localPath, cleanup, err := provider.Fetch(source.key, resolved)
+	if err != nil {
+		return "", fmt.Errorf("fetch backup: %w", err)
+	}
+	defer removeFetched(cleanup)
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("open backup: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(w, file); err != nil {
+		return "", fmt.Errorf("copy backup: %w", err)
+	}
+	return resolved, nil
+}
+
+// Delete removes a backup and its manifest. Only exact names are accepted,
+// never selectors, and pinned backups are refused unless force is set.
+func Delete(provider storage.Provider, database config.Database, filename string, force bool) error {
+	source, name := restoreSource(database, filename)
+	if storage.IsSelector(name) {
+		return fmt.Errorf("%s is a selector, delete needs the exact backup name", name)
+	}
+
+	files, err := provider.List(source.key)
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+	if !slices.ContainsFunc(files, func(file storage.FileInfo) bool { return file.Name == name }) {
+		return fmt.Errorf("backup %s not found", filename)
+	}
+
+	hasManifest := slices.ContainsFunc(files, func(file storage.FileInfo) bool { return file.Name == storage.ManifestName(name) })
+	if hasManifest && !force {
+		manifest, err := storage.LoadManifest(provider, source.key, name)
+		if err != nil {
+			return fmt.Errorf("load manifest: %w", err)
+		}
+		if manifest.Pin != nil {
+			return fmt.Errorf("backup %s is pinned by %s (%s); unpin it first or use --force", filename, manifest.Pin.Owner, manifest.Pin.Reason)
+		}
+	}
+
+	if err := provider.Delete(source.key, name); err != nil {
+		return fmt.Errorf("delete backup: %w", err)
+	}
+	if hasManifest {
+		if err := provider.Delete(source.key, storage.ManifestName(name)); err != nil {
+			return fmt.Errorf("delete manifest: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"docker-postgres-backuper/config"
+	"docker-postgres-backuper/storage"
+)
+
+func TestDeleteRemovesBackupWithManifest(t *testing.T) {
+	basePath := t.TempDir()
+	provider := storage.NewLocalProvider(basePath)
+	database := config.Database{Name: "users"}
+
+	for _, name := range []string{"file_manual_2025-07-04T09:00:00Z.dump", "file_manual_2025-07-04T09:00:00Z.dump.manifest.json", "file_daily_2025-07-05T09:00:00Z.dump"} {
+		path := filepath.Join(basePath, "users", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create directory: %v", err)
+		}
+		content := name
+		if storage.IsManifest(name) {
+			content = "{}"
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	if err := Delete(provider, database, "latest", false); err == nil {
+		t.Fatal("expected selectors to be rejected")
+	}
+	if err := Delete(provider, database, "file_manual_2025-07-06T09:00:00Z.dump", false); err == nil {
+		t.Fatal("expected missing backup to be reported")
+	}
+	if err := Delete(provider, database, "file_manual_2025-07-04T09:00:00Z.dump", false); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	files, err := provider.List("users")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "file_daily_2025-07-05T09:00:00Z.dump" {
+		t.Fatalf("unexpected remaining files: %+v", files)
+	}
+}
+
+func TestPinCreatesManifestAndUnpinReleases(t *testing.T) {
+	basePath := t.TempDir()
+	provider := storage.NewLocalProvider(basePath)
+	database := config.Database{Name: "users"}
+	name := "file_daily_2025-07-04T09:00:00Z.dump"
+	globals := "globals_daily_2025-07-04T09:00:00Z.sql"
+
+	for _, file := range []string{name, globals} {
+		path := filepath.Join(basePath, "users", file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatalf("write %s: %v", file, err)
+		}
+	}
+
+	if err := Pin(provider, database, "latest", "legal hold", "legal"); err == nil {
+		t.Fatal("expected selectors to be rejected")
+	}
+	if err := Pin(provider, database, name, "", "legal"); err == nil {
+		t.Fatal("expected a missing reason to be rejected")
+	}
+	if err := Unpin(provider, database, name); err == nil {
+		t.Fatal("expected unpinning a backup that is not pinned to fail")
+	}
+	if err := Pin(provider, database, name, "legal hold", "legal"); err != nil {
+		t.Fatalf("Pin returned error: %v", err)
+	}
+
+	manifest, err := storage.LoadManifest(provider, "users", name)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if manifest.Pin == nil || manifest.Pin.Reason != "legal hold" || manifest.Pin.Owner != "legal" {
+		t.Fatalf("unexpected pin: %+v", manifest.Pin)
+	}
+	if manifest.Type != "daily" || manifest.Size != int64(len(name)) {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	globalsManifest, err := storage.LoadManifest(provider, "users", globals)
+	if err != nil || globalsManifest.Pin == nil {
+		t.Fatalf("expected globals of the run to be pinned: %+v, %v", globalsManifest, err)
+	}
+	if err := Delete(provider, database, name, false); err == nil {
+		t.Fatal("expected pinned backup to be kept without force")
+	}
+
+	if err := Unpin(provider, database, name); err != nil {
+		t.Fatalf("Unpin returned error: %v", err)
+	}
+	manifest, err = storage.LoadManifest(provider, "users", name)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if manifest.Pin != nil {
+		t.Fatalf("expected pin to be removed, got %+v", manifest.Pin)
+	}
+	globalsManifest, err = storage.LoadManifest(provider, "users", globals)
+	if err != nil || globalsManifest.Pin != nil {
+		t.Fatalf("expected globals to be unpinned: %+v, %v", globalsManifest, err)
+	}
+}
+
+func TestPinWritesManifestOnlyToTargetsWithTheBackup(t *testing.T) {
+	primaryPath := t.TempDir()
+	replicaPath := t.TempDir()
+	provider := storage.NewMultiProvider([]storage.Target{
+		{Name: "local", Provider: storage.NewLocalProvider(primaryPath)},
+		{Name: "replica", Provider: storage.NewLocalProvider(replicaPath)},
+	})
+	database := config.Database{Name: "users"}
+	name := "file_daily_2025-07-04T09:00:00Z.dump"
+
+	path := filepath.Join(primaryPath, "users", name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	if err := os.MkdirAll(filepath.Join(replicaPath, "users"), 0o755); err != nil {
+		t.Fatalf("create directory: %v", err)
+	}
+
+	if err := Pin(provider, database, name, "legal hold", "legal"); err != nil {
+		t.Fatalf("Pin returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(primaryPath, "users", storage.ManifestName(name))); err != nil {
+		t.Fatalf("expected manifest next to the backup: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(replicaPath, "users", storage.ManifestName(name))); !os.IsNotExist(err) {
+		t.Fatalf("expected no manifest on the target without the backup, got %v", err)
+	}
+}